@@ -0,0 +1,253 @@
+package deque
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// special keys that hold the sequence numbers delimiting the deque, and the prefix of
+// the keys that hold its items
+const (
+	pFront = "front" // sequence number of the item at the front of the deque
+	pBack  = "back"  // sequence number the next item pushed to the back will be stored under
+	pItem  = "item"  // followed by the encoded sequence number of an item
+)
+
+// Deque is a double-ended queue backed by LevelDB.
+//
+// Items are stored under signed sequence numbers, encoded so that LevelDB orders them
+// numerically. Pushing to the front decrements the front sequence number and pushing to
+// the back increments the back one, so both ends grow without relocating any item, and
+// every operation is O(1).
+type Deque struct {
+	ns  []byte
+	ldb *leveldb.DB
+	l   sync.Mutex
+}
+
+func NewDeque(ns []byte, ldb *leveldb.DB) *Deque {
+	return &Deque{
+		ns:  ns,
+		ldb: ldb,
+	}
+}
+
+// PushFront adds v to the front of the deque
+func (dq *Deque) PushFront(v []byte) error {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return err
+	}
+
+	b.front--
+	batch := new(leveldb.Batch)
+	batch.Put(dq.item(b.front), v)
+	batch.Put(dq.special(pFront), encodeSeq(b.front))
+	return dq.write(batch)
+}
+
+// PushBack adds v to the back of the deque
+func (dq *Deque) PushBack(v []byte) error {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(dq.item(b.back), v)
+	batch.Put(dq.special(pBack), encodeSeq(b.back+1))
+	return dq.write(batch)
+}
+
+// PopFront removes and returns the item at the front of the deque
+func (dq *Deque) PopFront() ([]byte, error) {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return nil, err
+	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot pop from empty deque: %w", leveladt.ErrEmpty)
+	}
+
+	v, err := dq.get(b.front)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(dq.item(b.front))
+	batch.Put(dq.special(pFront), encodeSeq(b.front+1))
+	if err := dq.write(batch); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// PopBack removes and returns the item at the back of the deque
+func (dq *Deque) PopBack() ([]byte, error) {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return nil, err
+	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot pop from empty deque: %w", leveladt.ErrEmpty)
+	}
+
+	v, err := dq.get(b.back - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(dq.item(b.back - 1))
+	batch.Put(dq.special(pBack), encodeSeq(b.back-1))
+	if err := dq.write(batch); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// PeekFront returns the item at the front of the deque without removing it
+func (dq *Deque) PeekFront() ([]byte, error) {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return nil, err
+	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot peek into empty deque: %w", leveladt.ErrEmpty)
+	}
+	return dq.get(b.front)
+}
+
+// PeekBack returns the item at the back of the deque without removing it
+func (dq *Deque) PeekBack() ([]byte, error) {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return nil, err
+	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot peek into empty deque: %w", leveladt.ErrEmpty)
+	}
+	return dq.get(b.back - 1)
+}
+
+// Len returns the number of items in the deque
+func (dq *Deque) Len() (int64, error) {
+	dq.l.Lock()
+	defer dq.l.Unlock()
+
+	b, err := dq.bounds()
+	if err != nil {
+		return 0, err
+	}
+	return b.back - b.front, nil
+}
+
+// bounds holds the sequence numbers delimiting the items of a deque: items are stored
+// under every sequence number from front up to, but excluding, back
+type bounds struct {
+	front int64
+	back  int64
+}
+
+func (b bounds) empty() bool {
+	return b.front == b.back
+}
+
+// bounds reads the durable front and back sequence numbers of the deque
+func (dq *Deque) bounds() (bounds, error) {
+	front, err := dq.getSeq(dq.special(pFront))
+	if err != nil {
+		return bounds{}, err
+	}
+	back, err := dq.getSeq(dq.special(pBack))
+	if err != nil {
+		return bounds{}, err
+	}
+	return bounds{front: front, back: back}, nil
+}
+
+/*
+  convenience accessors that respect the deque namespace
+*/
+
+// get reads the item with sequence number seq, which must exist
+func (dq *Deque) get(seq int64) ([]byte, error) {
+	v, err := dq.ldb.Get(dq.item(seq), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, fmt.Errorf("missing deque item %d: %w", seq, leveladt.ErrCorrupt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leveldb get: %w", err)
+	}
+	return v, nil
+}
+
+// getSeq reads the sequence number stored under key, which is zero if the key was never written
+func (dq *Deque) getSeq(key []byte) (int64, error) {
+	v, err := dq.ldb.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodeSeq(v)
+}
+
+func (dq *Deque) write(batch *leveldb.Batch) error {
+	if err := dq.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
+// special encodes a special key or prefix, respecting the namespace of the deque
+func (dq *Deque) special(name string) []byte {
+	namespaced := make([]byte, len(dq.ns)+len(name))
+	copy(namespaced, dq.ns)
+	copy(namespaced[len(dq.ns):], name)
+	return namespaced
+}
+
+// item encodes the key of the item with sequence number seq
+func (dq *Deque) item(seq int64) []byte {
+	return append(dq.special(pItem), encodeSeq(seq)...)
+}
+
+// encodeSeq returns the big-endian encoding of seq with its sign bit flipped, which sorts
+// in numeric order
+func encodeSeq(seq int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(seq)^(1<<63))
+	return b
+}
+
+func decodeSeq(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("malformed sequence number of length %d: %w", len(b), leveladt.ErrCorrupt)
+	}
+	return int64(binary.BigEndian.Uint64(b) ^ (1 << 63)), nil
+}
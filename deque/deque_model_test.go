@@ -0,0 +1,339 @@
+package deque
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/commands"
+	"github.com/leanovate/gopter/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const testNamespace = "test"
+
+func TestDequeModel(t *testing.T) {
+	assert := assert.New(t)
+
+	test := &commands.ProtoCommands{
+		NewSystemUnderTestFunc: func(initialState commands.State) commands.SystemUnderTest {
+			dir, err := ioutil.TempDir("", "deque-*")
+			assert.Nil(err)
+
+			db, err := leveldb.OpenFile(dir, nil)
+			assert.Nil(err)
+
+			return &dequeController{
+				dir:   dir,
+				ldb:   db,
+				deque: NewDeque([]byte(testNamespace), db),
+			}
+		},
+		InitialStateGen: gen.Const(makeDequeModel()),
+		InitialPreConditionFunc: func(_ commands.State) bool {
+			return true
+		},
+		GenCommandFunc: func(st commands.State) gopter.Gen {
+			return gen.OneGenOf(
+				genPushCommand,
+				genPopCommand,
+				genPeekCommand,
+				genLenCommand,
+				genCrashCommand,
+			)
+		},
+	}
+
+	properties := gopter.NewProperties(gopter.DefaultTestParameters())
+	properties.Property("model", commands.Prop(test))
+	properties.TestingRun(t)
+}
+
+// genBytes generates arbitrary byte slices
+var genBytes gopter.Gen = gen.SliceOf(gen.UInt8())
+
+func genPushCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		pushCommand{
+			front: params.NextBool(),
+			x:     genBytes(params).Result.([]byte),
+		},
+		gopter.NoShrinker,
+	)
+}
+
+func genPopCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popCommand{front: params.NextBool()},
+		gopter.NoShrinker,
+	)
+}
+
+func genPeekCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		peekCommand{front: params.NextBool()},
+		gopter.NoShrinker,
+	)
+}
+
+func genLenCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		lenCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genCrashCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		crashCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+// pushCommand pushes x to the front of the deque if front is set, or else to the back
+type pushCommand struct {
+	front bool
+	x     []byte
+}
+
+func (cmd pushCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	dq := sut.(*dequeController).deque
+	push := dq.PushBack
+	if cmd.front {
+		push = dq.PushFront
+	}
+	if err := push(cmd.x); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd pushCommand) NextState(state commands.State) commands.State {
+	st := state.(dequeModel).clone()
+	if cmd.front {
+		st.PushFront(cmd.x)
+	} else {
+		st.PushBack(cmd.x)
+	}
+	return st
+}
+
+func (cmd pushCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd pushCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd pushCommand) String() string {
+	if cmd.front {
+		return fmt.Sprintf("pushFront(%q)", cmd.x)
+	}
+	return fmt.Sprintf("pushBack(%q)", cmd.x)
+}
+
+// popCommand pops from the front of the deque if front is set, or else from the back
+type popCommand struct {
+	front bool
+}
+
+func (cmd popCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	dq := sut.(*dequeController).deque
+	pop := dq.PopBack
+	if cmd.front {
+		pop = dq.PopFront
+	}
+	v, err := pop()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return v
+}
+
+func (cmd popCommand) NextState(state commands.State) commands.State {
+	st := state.(dequeModel).clone()
+	if cmd.front {
+		st.PopFront()
+	} else {
+		st.PopBack()
+	}
+	return st
+}
+
+func (cmd popCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(dequeModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popCommand) PreCondition(st commands.State) bool {
+	return st.(dequeModel).size() > 0
+}
+
+func (cmd popCommand) String() string {
+	if cmd.front {
+		return "popFront()"
+	}
+	return "popBack()"
+}
+
+// peekCommand peeks at the front of the deque if front is set, or else at the back
+type peekCommand struct {
+	front bool
+}
+
+func (cmd peekCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	dq := sut.(*dequeController).deque
+	peek := dq.PeekBack
+	if cmd.front {
+		peek = dq.PeekFront
+	}
+	v, err := peek()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return v
+}
+
+func (cmd peekCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd peekCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	mod := st.(dequeModel)
+	peek := mod.PeekBack
+	if cmd.front {
+		peek = mod.PeekFront
+	}
+
+	got := result.([]byte)
+	want, _ := peek()
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd peekCommand) PreCondition(st commands.State) bool {
+	return st.(dequeModel).size() > 0
+}
+
+func (cmd peekCommand) String() string {
+	if cmd.front {
+		return "peekFront()"
+	}
+	return "peekBack()"
+}
+
+type lenCommand struct{}
+
+func (cmd lenCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	dq := sut.(*dequeController).deque
+	n, err := dq.Len()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return n
+}
+
+func (cmd lenCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd lenCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.(int64)
+	want := int64(st.(dequeModel).size())
+	if got != want {
+		return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd lenCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd lenCommand) String() string {
+	return "len()"
+}
+
+type crashCommand struct{}
+
+func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	dc := sut.(*dequeController)
+
+	// close LevelDB connection and release resources
+	dc.ldb.Close()
+
+	// create new LevelDB connection
+	db, err := leveldb.OpenFile(dc.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	dc.ldb = db
+	dc.deque = NewDeque([]byte(testNamespace), db)
+
+	return nil
+}
+
+func (cmd crashCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd crashCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd crashCommand) PreCondition(st commands.State) bool {
+	return true
+}
+
+func (cmd crashCommand) String() string {
+	return "crash()"
+}
+
+var (
+	_ commands.Command = pushCommand{}
+	_ commands.Command = popCommand{}
+	_ commands.Command = peekCommand{}
+	_ commands.Command = lenCommand{}
+	_ commands.Command = crashCommand{}
+)
+
+// dequeController preserves the underlying reference to resources consumed by a
+// Deque to enable commands that represent restarts, filesystem failures, etc.
+type dequeController struct {
+	dir   string      // root of LevelDB database
+	ldb   *leveldb.DB // current LevelDB connection
+	deque *Deque      // deque under test
+}
@@ -0,0 +1,116 @@
+package deque
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func TestDeque(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	dq := NewDeque([]byte("test"), db)
+
+	_, err = dq.PopFront()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+	_, err = dq.PopBack()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+	_, err = dq.PeekFront()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+	_, err = dq.PeekBack()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	assert.Nil(dq.PushBack([]byte("b")))
+	assert.Nil(dq.PushFront([]byte("a")))
+	assert.Nil(dq.PushBack([]byte("c")))
+
+	n, err := dq.Len()
+	assert.Nil(err)
+	assert.Equal(int64(3), n)
+
+	front, err := dq.PeekFront()
+	assert.Nil(err)
+	assert.Equal([]byte("a"), front)
+
+	back, err := dq.PeekBack()
+	assert.Nil(err)
+	assert.Equal([]byte("c"), back)
+
+	got, err := dq.PopBack()
+	assert.Nil(err)
+	assert.Equal([]byte("c"), got)
+
+	got, err = dq.PopFront()
+	assert.Nil(err)
+	assert.Equal([]byte("a"), got)
+
+	got, err = dq.PopFront()
+	assert.Nil(err)
+	assert.Equal([]byte("b"), got)
+
+	n, err = dq.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+}
+
+// Items pushed to the front are stored under negative sequence numbers, which must sort
+// before the items pushed to the back
+func TestKeyOrdering(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	dq := NewDeque([]byte("test"), db)
+	for _, v := range []string{"c", "b", "a"} {
+		assert.Nil(dq.PushFront([]byte(v)))
+	}
+	for _, v := range []string{"d", "e"} {
+		assert.Nil(dq.PushBack([]byte(v)))
+	}
+
+	iter := db.NewIterator(util.BytesPrefix(dq.special(pItem)), nil)
+	defer iter.Release()
+
+	var got []string
+	for iter.Next() {
+		got = append(got, string(iter.Value()))
+	}
+	assert.Equal([]string{"a", "b", "c", "d", "e"}, got)
+}
+
+func TestNamespacing(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	a := NewDeque([]byte("a"), db)
+	b := NewDeque([]byte("b"), db)
+
+	assert.Nil(a.PushBack([]byte("foo")))
+
+	n, err := b.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+
+	_, err = b.PopFront()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+}
@@ -0,0 +1,72 @@
+package deque
+
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
+
+type dequeModel struct {
+	ls         []string
+	lastPopped []byte
+}
+
+func makeDequeModel() dequeModel {
+	return dequeModel{ls: make([]string, 0)}
+}
+
+func (mod *dequeModel) PushFront(x []byte) error {
+	mod.ls = append([]string{string(x)}, mod.ls...)
+	return nil
+}
+
+func (mod *dequeModel) PushBack(x []byte) error {
+	mod.ls = append(mod.ls, string(x))
+	return nil
+}
+
+func (mod *dequeModel) PopFront() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot pop from empty deque: %w", leveladt.ErrEmpty)
+	}
+
+	front := mod.ls[0]
+	mod.lastPopped = []byte(front)
+	mod.ls = mod.ls[1:]
+	return []byte(front), nil
+}
+
+func (mod *dequeModel) PopBack() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot pop from empty deque: %w", leveladt.ErrEmpty)
+	}
+
+	back := mod.ls[len(mod.ls)-1]
+	mod.lastPopped = []byte(back)
+	mod.ls = mod.ls[:len(mod.ls)-1]
+	return []byte(back), nil
+}
+
+func (mod dequeModel) PeekFront() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot peek into empty deque: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.ls[0]), nil
+}
+
+func (mod dequeModel) PeekBack() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot peek into empty deque: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.ls[len(mod.ls)-1]), nil
+}
+
+func (mod dequeModel) size() int {
+	return len(mod.ls)
+}
+
+func (mod dequeModel) clone() dequeModel {
+	cp := make([]string, len(mod.ls))
+	copy(cp, mod.ls)
+	return dequeModel{ls: cp, lastPopped: mod.lastPopped}
+}
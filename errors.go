@@ -0,0 +1,25 @@
+// Package leveladt provides abstract data structures implemented on top of LevelDB.
+//
+// The data structures live in subpackages. Errors they share, such as reading from an
+// empty structure, are defined here so callers can test for them with errors.Is
+// regardless of the structure that returned them.
+package leveladt
+
+import "errors"
+
+var (
+	// ErrEmpty is returned when an item is removed from, or inspected in, an empty structure
+	ErrEmpty = errors.New("leveladt: empty")
+
+	// ErrNotFound is returned when a requested element does not exist
+	ErrNotFound = errors.New("leveladt: not found")
+
+	// ErrOutOfRange is returned when an index falls outside the bounds of a structure
+	ErrOutOfRange = errors.New("leveladt: index out of range")
+
+	// ErrFull is returned when an item is added to a structure that is at capacity
+	ErrFull = errors.New("leveladt: full")
+
+	// ErrCorrupt is returned when stored data cannot be decoded
+	ErrCorrupt = errors.New("leveladt: corrupt data")
+)
@@ -0,0 +1,254 @@
+package list
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// special keys of a capped list
+const (
+	pCapacity = "capacity" // holds the maximum number of items
+	pHead     = "head"     // holds the sequence number of the oldest item
+	pTail     = "tail"     // holds the sequence number the next item is appended under
+	pEntry    = "entry"    // followed by the big-endian sequence number of an item, holds the item
+)
+
+// Capped is a list backed by LevelDB that keeps only its most recent items, like a ring
+// buffer. Items are stored under increasing sequence numbers, and appending to a full
+// list deletes the oldest item in the same write.
+type Capped struct {
+	ns  []byte
+	ldb *leveldb.DB
+	l   sync.Mutex
+
+	capacity int64
+	head     int64 // sequence number of the oldest item
+	tail     int64 // sequence number the next item is appended under
+}
+
+// NewCapped returns the capped list stored under namespace ns. The given capacity only
+// applies to a namespace that holds no capped list yet, and is persisted right away; a
+// list opened before keeps its persisted capacity, which can be changed with SetCapacity.
+func NewCapped(ns []byte, ldb *leveldb.DB, capacity int64) (*Capped, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("capacity of capped list must be positive, got %d", capacity)
+	}
+
+	cl := &Capped{
+		ns:       ns,
+		ldb:      ldb,
+		capacity: capacity,
+	}
+
+	persisted := false
+	for _, field := range []struct {
+		name string
+		v    *int64
+	}{
+		{pCapacity, &cl.capacity},
+		{pHead, &cl.head},
+		{pTail, &cl.tail},
+	} {
+		v, err := ldb.Get(cl.special(field.name), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("leveldb get: %w", err)
+		}
+		if *field.v, err = decodeLength(v); err != nil {
+			return nil, err
+		}
+		persisted = persisted || field.name == pCapacity
+	}
+
+	// the capacity of a new list is written before its first append, so that reopening
+	// it with another capacity does not change it
+	if !persisted {
+		if err := cl.commit(new(leveldb.Batch), cl.capacity, cl.head, cl.tail); err != nil {
+			return nil, err
+		}
+	}
+	return cl, nil
+}
+
+// Append the value v to the list, deleting the oldest item if the list is full
+func (cl *Capped) Append(v []byte) error {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	batch := new(leveldb.Batch)
+	batch.Put(cl.entry(cl.tail), v)
+
+	head := cl.head
+	for ; cl.tail+1-head > cl.capacity; head++ {
+		batch.Delete(cl.entry(head))
+	}
+	return cl.commit(batch, cl.capacity, head, cl.tail+1)
+}
+
+// Get returns the item at index i, where index 0 is the oldest item kept. Negative
+// indices count back from the newest item, so -1 is the most recent item. Get returns
+// an *IndexError if there is no item at i.
+func (cl *Capped) Get(i int64) ([]byte, error) {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	i, err := resolve("get", i, cl.tail-cl.head, false)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := cl.ldb.Get(cl.entry(cl.head+i), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, fmt.Errorf("missing capped list item %d: %w", cl.head+i, leveladt.ErrCorrupt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leveldb get: %w", err)
+	}
+	return v, nil
+}
+
+// Range returns the items from index start up to but excluding index end, oldest first.
+// Indices are resolved as they are by List.Range.
+func (cl *Capped) Range(start, end int64) ([][]byte, error) {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	length := cl.tail - cl.head
+	start, err := resolve("start range at", start, length, true)
+	if err != nil {
+		return nil, err
+	}
+	if end, err = resolve("end range at", end, length, true); err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("cannot read range [%d, %d) of list of length %d: %w", start, end, length, leveladt.ErrOutOfRange)
+	}
+
+	iter := cl.ldb.NewIterator(cl.entries(cl.head+start, cl.head+end), nil)
+	defer iter.Release()
+
+	items := make([][]byte, 0, end-start)
+	for iter.Next() {
+		items = append(items, append([]byte{}, iter.Value()...))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	if int64(len(items)) != end-start {
+		return nil, fmt.Errorf("found %d capped list items in range [%d, %d): %w", len(items), start, end, leveladt.ErrCorrupt)
+	}
+	return items, nil
+}
+
+// Trim keeps only the items from index start to index end, both inclusive, like the
+// Redis LTRIM command. Negative indices count back from the newest item. Indices past
+// either end of the list are clamped, and an empty range empties the list.
+func (cl *Capped) Trim(start, end int64) error {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	length := cl.tail - cl.head
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= length {
+		end = length - 1
+	}
+
+	head, tail := cl.head+start, cl.head+end+1
+	if start > end {
+		head, tail = cl.tail, cl.tail
+	}
+
+	batch := new(leveldb.Batch)
+	for seq := cl.head; seq < head; seq++ {
+		batch.Delete(cl.entry(seq))
+	}
+	for seq := tail; seq < cl.tail; seq++ {
+		batch.Delete(cl.entry(seq))
+	}
+
+	// the tail only moves back when items are trimmed from the new end, so sequence
+	// numbers are reused only for keys deleted in this batch
+	return cl.commit(batch, cl.capacity, head, tail)
+}
+
+// SetCapacity changes the maximum number of items of the list, deleting the oldest items
+// if there are more than capacity
+func (cl *Capped) SetCapacity(capacity int64) error {
+	if capacity <= 0 {
+		return fmt.Errorf("capacity of capped list must be positive, got %d", capacity)
+	}
+
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	batch := new(leveldb.Batch)
+	head := cl.head
+	for ; cl.tail-head > capacity; head++ {
+		batch.Delete(cl.entry(head))
+	}
+	return cl.commit(batch, capacity, head, cl.tail)
+}
+
+// Capacity returns the maximum number of items of the list
+func (cl *Capped) Capacity() int64 {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	return cl.capacity
+}
+
+// Len returns the number of items in the list
+func (cl *Capped) Len() int64 {
+	cl.l.Lock()
+	defer cl.l.Unlock()
+
+	return cl.tail - cl.head
+}
+
+// commit writes batch along with the new capacity, head and tail of the list, and adopts
+// them once the write succeeded
+func (cl *Capped) commit(batch *leveldb.Batch, capacity, head, tail int64) error {
+	batch.Put(cl.special(pCapacity), encodeLength(capacity))
+	batch.Put(cl.special(pHead), encodeLength(head))
+	batch.Put(cl.special(pTail), encodeLength(tail))
+	if err := cl.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+
+	cl.capacity, cl.head, cl.tail = capacity, head, tail
+	return nil
+}
+
+// entry encodes the key of the item with sequence number seq
+func (cl *Capped) entry(seq int64) []byte {
+	return appendID(cl.special(pEntry), uint64(seq))
+}
+
+// entries returns the range of the keys of the items with sequence numbers in
+// [start, end)
+func (cl *Capped) entries(start, end int64) *util.Range {
+	return &util.Range{Start: cl.entry(start), Limit: cl.entry(end)}
+}
+
+// special encodes a special key, respecting the namespace of the list
+func (cl *Capped) special(name string) []byte {
+	namespaced := make([]byte, len(cl.ns)+len(name))
+	copy(namespaced, cl.ns)
+	copy(namespaced[len(cl.ns):], name)
+	return namespaced
+}
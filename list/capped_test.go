@@ -0,0 +1,170 @@
+package list
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// values returns the items of cl, oldest first
+func values(t *testing.T, cl *Capped) []string {
+	items, err := cl.Range(0, cl.Len())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs := make([]string, 0, len(items))
+	for _, item := range items {
+		vs = append(vs, string(item))
+	}
+	return vs
+}
+
+func TestCapped(t *testing.T) {
+	openCapped := func(t *testing.T, capacity int64) (*Capped, *leveldb.DB) {
+		dir, err := ioutil.TempDir("", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := leveldb.OpenFile(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cl, err := NewCapped([]byte("test"), db, capacity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cl, db
+	}
+
+	appendAll := func(t *testing.T, cl *Capped, n int) {
+		for i := 0; i < n; i++ {
+			if err := cl.Append([]byte(fmt.Sprint(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Run("keeps the last items", func(t *testing.T) {
+		assert := assert.New(t)
+		cl, db := openCapped(t, 3)
+
+		appendAll(t, cl, 5)
+		assert.Equal(int64(3), cl.Len())
+		assert.Equal([]string{"2", "3", "4"}, values(t, cl))
+
+		v, err := cl.Get(-1)
+		assert.Nil(err)
+		assert.Equal("4", string(v))
+
+		_, err = cl.Get(3)
+		assert.True(errors.Is(err, leveladt.ErrOutOfRange))
+
+		// evicted items are deleted, not just hidden
+		_, err = db.Get(cl.entry(1), nil)
+		assert.Equal(leveldb.ErrNotFound, err)
+	})
+
+	t.Run("trim", func(t *testing.T) {
+		for _, tc := range []struct {
+			start, end int64
+			want       []string
+		}{
+			{1, 3, []string{"1", "2", "3"}},
+			{0, -2, []string{"0", "1", "2", "3"}},
+			{-2, -1, []string{"3", "4"}},
+			{-100, 100, []string{"0", "1", "2", "3", "4"}},
+			{3, 1, []string{}},
+			{5, 10, []string{}},
+		} {
+			t.Run(fmt.Sprintf("%d %d", tc.start, tc.end), func(t *testing.T) {
+				assert := assert.New(t)
+				cl, _ := openCapped(t, 10)
+
+				appendAll(t, cl, 5)
+				assert.Nil(cl.Trim(tc.start, tc.end))
+				assert.Equal(tc.want, values(t, cl))
+
+				// appending after a trim continues after the kept items
+				assert.Nil(cl.Append([]byte("x")))
+				assert.Equal(append(tc.want, "x"), values(t, cl))
+			})
+		}
+	})
+
+	t.Run("change capacity", func(t *testing.T) {
+		assert := assert.New(t)
+		cl, _ := openCapped(t, 5)
+
+		appendAll(t, cl, 5)
+		assert.Nil(cl.SetCapacity(2))
+		assert.Equal(int64(2), cl.Capacity())
+		assert.Equal([]string{"3", "4"}, values(t, cl))
+
+		assert.Nil(cl.SetCapacity(3))
+		assert.Nil(cl.Append([]byte("5")))
+		assert.Equal([]string{"3", "4", "5"}, values(t, cl))
+
+		assert.NotNil(cl.SetCapacity(0))
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		cl, err := NewCapped([]byte("test"), db, 3)
+		assert.Nil(err)
+		appendAll(t, cl, 5)
+		assert.Nil(cl.SetCapacity(4))
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		// the persisted capacity wins over the one given on reopen
+		cl, err = NewCapped([]byte("test"), db, 3)
+		assert.Nil(err)
+		assert.Equal(int64(4), cl.Capacity())
+		assert.Equal([]string{"2", "3", "4"}, values(t, cl))
+
+		assert.Nil(cl.Append([]byte("5")))
+		assert.Nil(cl.Append([]byte("6")))
+		assert.Equal([]string{"3", "4", "5", "6"}, values(t, cl))
+	})
+
+	t.Run("reopened before first append", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		_, err = NewCapped([]byte("test"), db, 2)
+		assert.Nil(err)
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		cl, err := NewCapped([]byte("test"), db, 5)
+		assert.Nil(err)
+		assert.Equal(int64(2), cl.Capacity())
+
+		appendAll(t, cl, 3)
+		assert.Equal([]string{"1", "2"}, values(t, cl))
+	})
+}
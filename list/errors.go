@@ -0,0 +1,44 @@
+package list
+
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
+
+// IndexError is returned when an index falls outside a list. It wraps
+// leveladt.ErrOutOfRange, so callers that only care about the kind of failure can test
+// for it with errors.Is.
+type IndexError struct {
+	Op     string // operation that was attempted, such as "get" or "insert at"
+	Index  int64  // index as it was given, before negative indices were resolved
+	Length int64  // length of the list at the time of the operation
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("cannot %s index %d of list of length %d: %v", e.Op, e.Index, e.Length, leveladt.ErrOutOfRange)
+}
+
+func (e *IndexError) Unwrap() error {
+	return leveladt.ErrOutOfRange
+}
+
+// resolve returns the non-negative index that i refers to in a list of the given
+// length, counting negative indices back from the end so that -1 is the last item. The
+// index must fall in [0, length), or in [0, length] if inclusive is set, otherwise
+// resolve returns an *IndexError for op.
+func resolve(op string, i, length int64, inclusive bool) (int64, error) {
+	j := i
+	if j < 0 {
+		j += length
+	}
+
+	limit := length
+	if inclusive {
+		limit++
+	}
+	if j < 0 || j >= limit {
+		return 0, &IndexError{Op: op, Index: i, Length: length}
+	}
+	return j, nil
+}
@@ -0,0 +1,214 @@
+package list
+
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// Iterator walks the items of a list, as they were when the iterator was created, one
+// page read at a time. An Iterator must be released once it is no longer used.
+type Iterator struct {
+	ls      *List
+	snap    *leveldb.Snapshot
+	iter    iterator.Iterator
+	reverse bool
+
+	// page directory and length of the list at the time of the snapshot
+	pages  []page
+	length int64
+
+	items   [][]byte // items of the page under iter
+	base    int64    // index of the first item of items
+	off     int      // offset of the current item in items
+	started bool
+	valid   bool
+	err     error
+}
+
+// Iterator returns an iterator over a snapshot of the list that walks it from the first
+// item to the last, so updates that happen while iterating are not seen
+func (ls *List) Iterator() (*Iterator, error) {
+	return ls.iterator(false)
+}
+
+// ReverseIterator returns an iterator over a snapshot of the list that walks it from the
+// last item to the first
+func (ls *List) ReverseIterator() (*Iterator, error) {
+	return ls.iterator(true)
+}
+
+func (ls *List) iterator(reverse bool) (*Iterator, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	snap, err := ls.ldb.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("leveldb snapshot: %w", err)
+	}
+
+	return &Iterator{
+		ls:      ls,
+		snap:    snap,
+		iter:    snap.NewIterator(ls.prefix(pPage), nil),
+		reverse: reverse,
+		pages:   append([]page{}, ls.pages...),
+		length:  ls.length,
+	}, nil
+}
+
+// Next moves the iterator to the next item in its direction, and returns false once
+// there are no more items or an error occurred
+func (it *Iterator) Next() bool {
+	if it.err != nil || (it.started && !it.valid) {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		if it.reverse {
+			it.valid = it.load(it.iter.Last())
+			it.base = it.length - int64(len(it.items))
+			it.off = len(it.items) - 1
+		} else {
+			it.valid = it.load(it.iter.First())
+			it.base, it.off = 0, 0
+		}
+		return it.valid
+	}
+
+	if it.reverse {
+		it.off--
+		if it.off < 0 {
+			it.valid = it.load(it.iter.Prev())
+			it.base -= int64(len(it.items))
+			it.off = len(it.items) - 1
+		}
+	} else {
+		it.off++
+		if it.off >= len(it.items) {
+			it.base += int64(len(it.items))
+			it.valid = it.load(it.iter.Next())
+			it.off = 0
+		}
+	}
+	return it.valid
+}
+
+// SeekIndex moves the iterator to the item at index i, and returns false if there is no
+// such item. Negative indices count back from the end of the list. Next continues from i
+// in the direction of the iterator. It is not named Seek because go vet expects a Seek
+// method taking an int64 to implement io.Seeker.
+func (it *Iterator) SeekIndex(i int64) bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.started = true
+	i, err := resolve("seek", i, it.length, false)
+	if err != nil {
+		it.valid = false
+		return false
+	}
+
+	base := i
+	p := 0
+	for ; base >= int64(it.pages[p].n); p++ {
+		base -= int64(it.pages[p].n)
+	}
+
+	if it.valid = it.load(it.iter.Seek(it.ls.page(it.pages[p].id))); !it.valid {
+		return false
+	}
+	if int64(len(it.items)) != int64(it.pages[p].n) {
+		it.err = fmt.Errorf("list page %d holds %d items, expected %d: %w", it.pages[p].id, len(it.items), it.pages[p].n, leveladt.ErrCorrupt)
+		it.valid = false
+		return false
+	}
+	it.base, it.off = i-base, int(base)
+	return true
+}
+
+// load decodes the page under the underlying iterator if ok, and returns whether a page
+// with items was loaded
+func (it *Iterator) load(ok bool) bool {
+	it.items = nil
+	if !ok {
+		if err := it.iter.Error(); err != nil {
+			it.err = fmt.Errorf("leveldb iterate: %w", err)
+		}
+		return false
+	}
+
+	// the value is only valid until the underlying iterator moves, so the page is decoded
+	// from a copy
+	items, err := decodePage(append([]byte{}, it.iter.Value()...))
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if len(items) == 0 {
+		it.err = fmt.Errorf("empty list page: %w", leveladt.ErrCorrupt)
+		return false
+	}
+	it.items = items
+	return true
+}
+
+// Index returns the index of the current item
+func (it *Iterator) Index() int64 {
+	return it.base + int64(it.off)
+}
+
+// Value returns the current item, or nil if the iterator is not at an item
+func (it *Iterator) Value() []byte {
+	if !it.valid {
+		return nil
+	}
+	return it.items[it.off]
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Release releases the snapshot held by the iterator
+func (it *Iterator) Release() {
+	it.iter.Release()
+	it.snap.Release()
+}
+
+// Range returns the items from index start up to but excluding index end, read from a
+// single snapshot of the list. Negative indices count back from the end of the list, so
+// Range(-2, -1) holds the item before the last. Range returns an *IndexError if either
+// index falls outside the list, and an error wrapping leveladt.ErrOutOfRange if end
+// comes before start.
+func (ls *List) Range(start, end int64) ([][]byte, error) {
+	it, err := ls.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	if start, err = resolve("start range at", start, it.length, true); err != nil {
+		return nil, err
+	}
+	if end, err = resolve("end range at", end, it.length, true); err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("cannot read range [%d, %d) of list of length %d: %w", start, end, it.length, leveladt.ErrOutOfRange)
+	}
+
+	items := make([][]byte, 0, end-start)
+	for ok := start < end && it.SeekIndex(start); ok && int64(len(items)) < end-start; ok = it.Next() {
+		items = append(items, it.Value())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,170 @@
+package list
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// openPagedList returns a list of n items "0", "1", ... spread over pages of size 3
+func openPagedList(t *testing.T, n int) *List {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls, err := NewList([]byte("test"), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.pageSize = 3
+
+	for i := 0; i < n; i++ {
+		if err := ls.Append([]byte(fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ls
+}
+
+func TestIterator(t *testing.T) {
+	t.Run("forward", func(t *testing.T) {
+		assert := assert.New(t)
+		ls := openPagedList(t, 8)
+
+		it, err := ls.Iterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		var want int64
+		for it.Next() {
+			assert.Equal(want, it.Index())
+			assert.Equal(fmt.Sprint(want), string(it.Value()))
+			want++
+		}
+		assert.Nil(it.Err())
+		assert.Equal(int64(8), want)
+		assert.False(it.Next())
+		assert.Nil(it.Value())
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		assert := assert.New(t)
+		ls := openPagedList(t, 8)
+
+		it, err := ls.ReverseIterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		want := int64(7)
+		for it.Next() {
+			assert.Equal(want, it.Index())
+			assert.Equal(fmt.Sprint(want), string(it.Value()))
+			want--
+		}
+		assert.Nil(it.Err())
+		assert.Equal(int64(-1), want)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert := assert.New(t)
+		ls := openPagedList(t, 0)
+
+		for _, reverse := range []bool{false, true} {
+			it, err := ls.iterator(reverse)
+			assert.Nil(err)
+			assert.Nil(it.Value())
+			assert.False(it.Next())
+			assert.Nil(it.Value())
+			assert.False(it.SeekIndex(0))
+			assert.Nil(it.Err())
+			it.Release()
+		}
+	})
+
+	t.Run("seek", func(t *testing.T) {
+		assert := assert.New(t)
+		ls := openPagedList(t, 8)
+
+		it, err := ls.Iterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		assert.True(it.SeekIndex(4))
+		assert.Equal(int64(4), it.Index())
+		assert.Equal("4", string(it.Value()))
+		assert.True(it.Next())
+		assert.Equal("5", string(it.Value()))
+
+		assert.True(it.SeekIndex(1))
+		assert.Equal("1", string(it.Value()))
+
+		assert.False(it.SeekIndex(8))
+		assert.False(it.Next())
+
+		rit, err := ls.ReverseIterator()
+		assert.Nil(err)
+		defer rit.Release()
+
+		assert.True(rit.SeekIndex(3))
+		var got []string
+		for ok := true; ok; ok = rit.Next() {
+			got = append(got, string(rit.Value()))
+		}
+		assert.Nil(rit.Err())
+		assert.Equal([]string{"3", "2", "1", "0"}, got)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		assert := assert.New(t)
+		ls := openPagedList(t, 4)
+
+		it, err := ls.Iterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		assert.Nil(ls.Insert(0, []byte("x")))
+		assert.Nil(ls.Set(2, []byte("y")))
+		_, err = ls.Pop()
+		assert.Nil(err)
+
+		var got []string
+		for it.Next() {
+			got = append(got, string(it.Value()))
+		}
+		assert.Nil(it.Err())
+		assert.Equal([]string{"0", "1", "2", "3"}, got)
+	})
+}
+
+func TestRange(t *testing.T) {
+	assert := assert.New(t)
+	ls := openPagedList(t, 8)
+
+	got, err := ls.Range(2, 7)
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("2"), []byte("3"), []byte("4"), []byte("5"), []byte("6")}, got)
+
+	got, err = ls.Range(0, 8)
+	assert.Nil(err)
+	assert.Len(got, 8)
+
+	got, err = ls.Range(3, 3)
+	assert.Nil(err)
+	assert.Empty(got)
+
+	for _, r := range [][2]int64{{-1, 2}, {2, 1}, {0, 9}} {
+		_, err = ls.Range(r[0], r[1])
+		assert.True(errors.Is(err, leveladt.ErrOutOfRange))
+	}
+}
@@ -2,37 +2,351 @@ package list
 
 import (
 	"encoding/binary"
+	"fmt"
+	"sync"
 
+	"github.com/lyonssp/leveladt"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// special key that stores the durable length of the list
+const pLength = "length"
+
+// List is a list backed by LevelDB.
+//
+// Items are stored in pages of up to pageSize consecutive items, under page IDs that
+// sort in list order and leave gaps between each other. Inserting or deleting an item
+// rewrites a single page, splitting it in two when it overflows, so updates anywhere in
+// the list are O(pageSize) rather than O(n). The number of items in every page is also
+// kept in memory to find the page that holds an index without reading the others.
 type List struct {
 	ns  []byte
 	ldb *leveldb.DB
+	l   sync.Mutex
+
+	length int64
+	pages  []page // directory of the pages of the list, in list order
+	marked bool   // whether the current layout version is recorded under the namespace
 
-	length int
+	// maximum number of items in a page, replaced by tests
+	pageSize int
+}
+
+// NewList returns the list stored under namespace ns, restoring its length and pages if
+// the list was written to by a previous process. Lists written in the dense or paged
+// layouts of earlier releases are rewritten to the current layout.
+func NewList(ns []byte, ldb *leveldb.DB) (*List, error) {
+	ls := &List{
+		ns:       ns,
+		ldb:      ldb,
+		pageSize: defaultPageSize,
+	}
+
+	layout, err := ls.layout()
+	if err != nil {
+		return nil, err
+	}
+
+	switch layout {
+	case layoutVersion:
+		ls.marked = true
+		if ls.length, err = ls.getLength(ls.pLength()); err != nil {
+			return nil, err
+		}
+		if err := ls.loadPages(); err != nil {
+			return nil, err
+		}
+	case layoutPaged:
+		if err := ls.migratePaged(); err != nil {
+			return nil, err
+		}
+	default:
+		if ls.length, err = ls.getLength(ls.plain(pLength)); err != nil {
+			return nil, err
+		}
+		if ls.length > 0 {
+			if err := ls.migrateDense(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ls, nil
 }
 
 // Append the value v to the list
 func (ls *List) Append(v []byte) error {
-	k := ls.key(int64(ls.length))
-	ls.length++
-	return ls.ldb.Put(k, v, nil)
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	return ls.insert(ls.length, v)
 }
 
-// Get return the item at index i
+// Get return the item at index i. Negative indices count back from the end of the list,
+// so -1 is the last item. Get returns an *IndexError if there is no item at i.
 func (ls *List) Get(i int64) ([]byte, error) {
-	v, err := ls.ldb.Get(ls.key(i), nil)
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	i, err := resolve("get", i, ls.length, false)
+	if err != nil {
+		return nil, err
+	}
+
+	p, off := ls.locate(i)
+	items, err := ls.readPage(ls.pages[p].id)
+	if err != nil {
+		return nil, err
+	}
+	return items[off], nil
+}
+
+// Set overwrites the item at index i with v. Negative indices count back from the end
+// of the list.
+func (ls *List) Set(i int64, v []byte) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	i, err := resolve("set", i, ls.length, false)
+	if err != nil {
+		return err
+	}
+
+	p, off := ls.locate(i)
+	items, err := ls.readPage(ls.pages[p].id)
+	if err != nil {
+		return err
+	}
+	items[off] = v
+
+	batch := new(leveldb.Batch)
+	ls.putPage(batch, ls.pages[p].id, items)
+	return ls.commit(batch, ls.pages, ls.length)
+}
+
+// Insert v at index i, shifting the item at index i and every item after it up by one.
+// An index equal to the length of the list appends v. Negative indices count back from
+// the end of the list, so inserting at -1 places v before the last item.
+func (ls *List) Insert(i int64, v []byte) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	i, err := resolve("insert at", i, ls.length, true)
+	if err != nil {
+		return err
+	}
+	return ls.insert(i, v)
+}
+
+// Delete removes the item at index i, shifting every item after it down by one.
+// Negative indices count back from the end of the list.
+func (ls *List) Delete(i int64) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	i, err := resolve("delete", i, ls.length, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = ls.remove(i)
+	return err
+}
+
+// Pop removes and returns the last item of the list
+func (ls *List) Pop() ([]byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	if ls.length == 0 {
+		return nil, fmt.Errorf("cannot pop from empty list: %w", leveladt.ErrEmpty)
+	}
+	return ls.remove(ls.length - 1)
+}
+
+// PopFront removes and returns the first item of the list
+func (ls *List) PopFront() ([]byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	if ls.length == 0 {
+		return nil, fmt.Errorf("cannot pop from empty list: %w", leveladt.ErrEmpty)
+	}
+	return ls.remove(0)
+}
+
+// insert v at index i, which must be at most the length of the list. The caller must
+// hold the list lock.
+func (ls *List) insert(i int64, v []byte) error {
+	pages := append([]page{}, ls.pages...)
+	batch := new(leveldb.Batch)
+
+	// append to a new page once the last one is full
+	if i == ls.length && (len(pages) == 0 || pages[len(pages)-1].n >= ls.pageSize) {
+		id := uint64(pageGap)
+		if len(pages) > 0 {
+			id = pages[len(pages)-1].id + pageGap
+		}
+		pages = append(pages, page{id: id, n: 1})
+		ls.putPage(batch, id, [][]byte{v})
+		return ls.commit(batch, pages, ls.length+1)
+	}
+
+	p, off := len(pages)-1, pages[len(pages)-1].n
+	if i < ls.length {
+		p, off = ls.locate(i)
+	}
+
+	items, err := ls.readPage(pages[p].id)
+	if err != nil {
+		return err
+	}
+	items = append(items, nil)
+	copy(items[off+1:], items[off:])
+	items[off] = v
+
+	if len(items) <= ls.pageSize {
+		pages[p].n++
+		ls.putPage(batch, pages[p].id, items)
+		return ls.commit(batch, pages, ls.length+1)
+	}
+
+	// split the overflowing page, giving its second half an ID in the gap after it
+	id, err := ls.splitID(batch, pages, p)
+	if err != nil {
+		return err
+	}
+	half := len(items) / 2
+	pages[p].n = half
+	pages = append(pages[:p+1], append([]page{{id: id, n: len(items) - half}}, pages[p+1:]...)...)
+	ls.putPage(batch, pages[p].id, items[:half])
+	ls.putPage(batch, id, items[half:])
+	return ls.commit(batch, pages, ls.length+1)
+}
+
+// remove deletes and returns the item at index i, which must be in range. The caller
+// must hold the list lock.
+func (ls *List) remove(i int64) ([]byte, error) {
+	pages := append([]page{}, ls.pages...)
+	p, off := ls.locate(i)
+
+	items, err := ls.readPage(pages[p].id)
 	if err != nil {
 		return nil, err
 	}
+	v := items[off]
+	items = append(items[:off], items[off+1:]...)
 
+	batch := new(leveldb.Batch)
+	if len(items) == 0 {
+		ls.deletePage(batch, pages[p].id)
+		pages = append(pages[:p], pages[p+1:]...)
+	} else {
+		ls.putPage(batch, pages[p].id, items)
+		pages[p].n--
+	}
+
+	if err := ls.commit(batch, pages, ls.length-1); err != nil {
+		return nil, err
+	}
 	return v, nil
 }
 
+// commit writes batch along with the new length of the list, and adopts the new length
+// and page directory once the write succeeded
+func (ls *List) commit(batch *leveldb.Batch, pages []page, length int64) error {
+	batch.Put(ls.pLength(), encodeLength(length))
+	if !ls.marked {
+		batch.Put(ls.special(pLayout), encodeLength(layoutVersion))
+	}
+	if err := ls.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+
+	ls.pages, ls.length, ls.marked = pages, length, true
+	return nil
+}
+
+// layout reads the key layout version recorded under the namespace, which is zero if
+// none was recorded. Layout versions before the current one were recorded under the
+// plain key.
+func (ls *List) layout() (int64, error) {
+	for _, key := range [][]byte{ls.special(pLayout), ls.plain(pLayout)} {
+		v, err := ls.ldb.Get(key, nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("leveldb get: %w", err)
+		}
+		return decodeLength(v)
+	}
+	return 0, nil
+}
+
+// getLength reads the length stored under key, which is zero if the key was never written
+func (ls *List) getLength(key []byte) (int64, error) {
+	v, err := ls.ldb.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodeLength(v)
+}
+
+// key encodes index i in the dense layout of layout version 1, as a fixed-width
+// big-endian suffix of the list namespace
 func (ls *List) key(i int64) []byte {
 	namespaced := make([]byte, len(ls.ns)+8) // namespace length plus 64 bit integer
 	copy(namespaced[:len(ls.ns)], ls.ns)
-	binary.PutVarint(namespaced, i)
+	binary.BigEndian.PutUint64(namespaced[len(ls.ns):], uint64(i))
+	return namespaced
+}
+
+// pLength encodes the pLength constant, respecting the namespace of the list
+func (ls *List) pLength() []byte {
+	return ls.special(pLength)
+}
+
+// special encodes a special key, respecting the namespace of the list. The namespace is
+// preceded by its uvarint length, so the keys of a list never start with the keys of a
+// list whose namespace extends its own, such as a list named "xsize" next to "x".
+func (ls *List) special(name string) []byte {
+	var n [binary.MaxVarintLen64]byte
+	k := binary.PutUvarint(n[:], uint64(len(ls.ns)))
+
+	namespaced := make([]byte, k+len(ls.ns)+len(name))
+	copy(namespaced, n[:k])
+	copy(namespaced[k:], ls.ns)
+	copy(namespaced[k+len(ls.ns):], name)
+	return namespaced
+}
+
+// plain encodes a special key of the layouts before the current one, which appended
+// name to the namespace directly
+func (ls *List) plain(name string) []byte {
+	namespaced := make([]byte, len(ls.ns)+len(name))
+	copy(namespaced, ls.ns)
+	copy(namespaced[len(ls.ns):], name)
 	return namespaced
 }
+
+// prefix returns the range of the keys that start with the special key name
+func (ls *List) prefix(name string) *util.Range {
+	return util.BytesPrefix(ls.special(name))
+}
+
+func encodeLength(n int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(n))
+	return b
+}
+
+func decodeLength(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("malformed list length of size %d: %w", len(b), leveladt.ErrCorrupt)
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
@@ -1,6 +1,7 @@
 package list
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"reflect"
@@ -13,20 +14,26 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
+const testNamespace = "test"
+
 func TestListModel(t *testing.T) {
 	assert := assert.New(t)
 
 	test := &commands.ProtoCommands{
 		NewSystemUnderTestFunc: func(initialState commands.State) commands.SystemUnderTest {
-			dir, err := ioutil.TempDir("", "test")
+			dir, err := ioutil.TempDir("", "list-*")
 			assert.Nil(err)
 
 			db, err := leveldb.OpenFile(dir, nil)
 			assert.Nil(err)
 
-			return List{
-				ns:  []byte("test"),
-				ldb: db,
+			ls, err := newModelList(db)
+			assert.Nil(err)
+
+			return &listController{
+				dir:  dir,
+				ldb:  db,
+				list: ls,
 			}
 		},
 		InitialStateGen: gen.Const(makeListModel()),
@@ -34,7 +41,17 @@ func TestListModel(t *testing.T) {
 			return true
 		},
 		GenCommandFunc: func(st commands.State) gopter.Gen {
-			return gen.OneGenOf(genAppendCommand, genGetCommand(st))
+			return gen.OneGenOf(
+				genAppendCommand,
+				genGetCommand(st),
+				genSetCommand(st),
+				genInsertCommand(st),
+				genDeleteCommand(st),
+				genPopCommand,
+				genPopFrontCommand,
+				genRangeCommand(st),
+				genCrashCommand,
+			)
 		},
 	}
 
@@ -60,7 +77,8 @@ var genGetCommand = func(st commands.State) gopter.Gen {
 			return gopter.NewEmptyResult(reflect.TypeOf(getCommand{}))
 		}
 
-		index := params.Rng.Int63n(length)
+		// negative indices count back from the end of the list
+		index := params.Rng.Int63n(2*length) - length
 
 		return gopter.NewGenResult(
 			getCommand{i: index},
@@ -69,12 +87,94 @@ var genGetCommand = func(st commands.State) gopter.Gen {
 	}
 }
 
+var genSetCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		length := int64(st.(listModel).size())
+		if length == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(setCommand{}))
+		}
+
+		return gopter.NewGenResult(
+			setCommand{
+				i: params.Rng.Int63n(2*length) - length,
+				x: []byte(gen.Identifier()(params).Result.(string)),
+			},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genInsertCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		length := int64(st.(listModel).size())
+
+		// inserting at the length of the list appends
+		return gopter.NewGenResult(
+			insertCommand{
+				i: params.Rng.Int63n(2*length+1) - length,
+				x: []byte(gen.Identifier()(params).Result.(string)),
+			},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genDeleteCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		length := int64(st.(listModel).size())
+		if length == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(deleteCommand{}))
+		}
+
+		return gopter.NewGenResult(
+			deleteCommand{i: params.Rng.Int63n(2*length) - length},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genRangeCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		length := int64(st.(listModel).size())
+		start := params.Rng.Int63n(length + 1)
+
+		return gopter.NewGenResult(
+			rangeCommand{
+				start: start,
+				end:   start + params.Rng.Int63n(length-start+1),
+			},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genPopCommand gopter.Gen = func(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+var genPopFrontCommand gopter.Gen = func(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popFrontCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+var genCrashCommand gopter.Gen = func(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		crashCommand{},
+		gopter.NoShrinker,
+	)
+}
+
 type appendCommand struct {
 	x []byte
 }
 
 func (cmd appendCommand) Run(sut commands.SystemUnderTest) commands.Result {
-	ls := sut.(List)
+	ls := sut.(*listController).list
 	err := ls.Append(cmd.x)
 	if err != nil {
 		return commands.Result(err)
@@ -94,7 +194,7 @@ func (cmd appendCommand) PreCondition(_ commands.State) bool {
 
 func (cmd appendCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
 	if e, ok := result.(error); ok {
-		return &gopter.PropResult{Error: e}
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
 	}
 	return gopter.NewPropResult(true, "")
 }
@@ -108,36 +208,340 @@ type getCommand struct {
 }
 
 func (cmd getCommand) Run(sut commands.SystemUnderTest) commands.Result {
-	ls := sut.(List)
-	_, err := ls.Get(cmd.i)
+	ls := sut.(*listController).list
+	v, err := ls.Get(cmd.i)
 	if err != nil {
 		return commands.Result(err)
 	}
-	return nil
+	return v
 }
 
 func (cmd getCommand) NextState(state commands.State) commands.State {
+	return state.(listModel).clone()
+}
+
+func (cmd getCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want, _ := st.(listModel).Get(cmd.i)
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%s != %s", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd getCommand) PreCondition(st commands.State) bool {
+	n := int64(st.(listModel).size())
+	return -n <= cmd.i && cmd.i < n
+}
+
+func (cmd getCommand) String() string {
+	return fmt.Sprintf("get(%d)", cmd.i)
+}
+
+type setCommand struct {
+	i int64
+	x []byte
+}
+
+func (cmd setCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	if err := ls.Set(cmd.i, cmd.x); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd setCommand) NextState(state commands.State) commands.State {
 	st := state.(listModel).clone()
-	st.Get(cmd.i)
+	st.Set(cmd.i, cmd.x)
 	return st
 }
 
-func (cmd getCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+func (cmd setCommand) PreCondition(st commands.State) bool {
+	n := int64(st.(listModel).size())
+	return -n <= cmd.i && cmd.i < n
+}
+
+func (cmd setCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
 	if e, ok := result.(error); ok {
-		return &gopter.PropResult{Error: e}
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
 	}
 	return gopter.NewPropResult(true, "")
 }
 
-func (cmd getCommand) PreCondition(_ commands.State) bool {
+func (cmd setCommand) String() string {
+	return fmt.Sprintf("set(%d, %s)", cmd.i, string(cmd.x))
+}
+
+type insertCommand struct {
+	i int64
+	x []byte
+}
+
+func (cmd insertCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	if err := ls.Insert(cmd.i, cmd.x); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd insertCommand) NextState(state commands.State) commands.State {
+	st := state.(listModel).clone()
+	st.Insert(cmd.i, cmd.x)
+	return st
+}
+
+func (cmd insertCommand) PreCondition(st commands.State) bool {
+	n := int64(st.(listModel).size())
+	return -n <= cmd.i && cmd.i <= n
+}
+
+func (cmd insertCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd insertCommand) String() string {
+	return fmt.Sprintf("insert(%d, %s)", cmd.i, string(cmd.x))
+}
+
+type deleteCommand struct {
+	i int64
+}
+
+func (cmd deleteCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	if err := ls.Delete(cmd.i); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd deleteCommand) NextState(state commands.State) commands.State {
+	st := state.(listModel).clone()
+	st.Delete(cmd.i)
+	return st
+}
+
+func (cmd deleteCommand) PreCondition(st commands.State) bool {
+	n := int64(st.(listModel).size())
+	return -n <= cmd.i && cmd.i < n
+}
+
+func (cmd deleteCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd deleteCommand) String() string {
+	return fmt.Sprintf("delete(%d)", cmd.i)
+}
+
+type popCommand struct{}
+
+func (cmd popCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	v, err := ls.Pop()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return v
+}
+
+func (cmd popCommand) NextState(state commands.State) commands.State {
+	st := state.(listModel).clone()
+	st.Pop()
+	return st
+}
+
+func (cmd popCommand) PreCondition(st commands.State) bool {
+	return st.(listModel).size() > 0
+}
+
+func (cmd popCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(listModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%s != %s", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popCommand) String() string {
+	return "pop()"
+}
+
+type popFrontCommand struct{}
+
+func (cmd popFrontCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	v, err := ls.PopFront()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return v
+}
+
+func (cmd popFrontCommand) NextState(state commands.State) commands.State {
+	st := state.(listModel).clone()
+	st.PopFront()
+	return st
+}
+
+func (cmd popFrontCommand) PreCondition(st commands.State) bool {
+	return st.(listModel).size() > 0
+}
+
+func (cmd popFrontCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(listModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%s != %s", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popFrontCommand) String() string {
+	return "popFront()"
+}
+
+type rangeCommand struct {
+	start, end int64
+}
+
+func (cmd rangeCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	ls := sut.(*listController).list
+	vs, err := ls.Range(cmd.start, cmd.end)
+	if err != nil {
+		return commands.Result(err)
+	}
+	return vs
+}
+
+func (cmd rangeCommand) NextState(state commands.State) commands.State {
+	return state.(listModel).clone()
+}
+
+func (cmd rangeCommand) PreCondition(st commands.State) bool {
+	return cmd.end <= int64(st.(listModel).size())
+}
+
+func (cmd rangeCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([][]byte)
+	want := st.(listModel).Range(cmd.start, cmd.end)
+	if len(got) != len(want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("got %d items, want %d", len(got), len(want)))
+	}
+	for k := range got {
+		if !bytes.Equal(got[k], want[k]) {
+			return gopter.NewPropResult(false, fmt.Sprintf("%s != %s at index %d", got[k], want[k], cmd.start+int64(k)))
+		}
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd rangeCommand) String() string {
+	return fmt.Sprintf("range(%d, %d)", cmd.start, cmd.end)
+}
+
+type crashCommand struct{}
+
+func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	lc := sut.(*listController)
+
+	// close LevelDB connection and release resources
+	lc.ldb.Close()
+
+	// create new LevelDB connection
+	db, err := leveldb.OpenFile(lc.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	ls, err := newModelList(db)
+	if err != nil {
+		return err
+	}
+
+	lc.ldb = db
+	lc.list = ls
+
+	return nil
+}
+
+func (cmd crashCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd crashCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd crashCommand) PreCondition(_ commands.State) bool {
 	return true
 }
 
-func (cmd getCommand) String() string {
-	return fmt.Sprintf("get(%d)", cmd.i)
+func (cmd crashCommand) String() string {
+	return "crash()"
 }
 
 var (
 	_ commands.Command = appendCommand{}
 	_ commands.Command = getCommand{}
+	_ commands.Command = setCommand{}
+	_ commands.Command = insertCommand{}
+	_ commands.Command = deleteCommand{}
+	_ commands.Command = popCommand{}
+	_ commands.Command = popFrontCommand{}
+	_ commands.Command = rangeCommand{}
+	_ commands.Command = crashCommand{}
 )
+
+// listController preserves the underlying reference to resources consumed by a
+// List to enable commands that represent restarts, filesystem failures, etc.
+type listController struct {
+	dir  string      // root of LevelDB database
+	ldb  *leveldb.DB // current LevelDB connection
+	list *List       // list under test
+}
+
+// modelPageSize is the page size of lists under test, small enough for short command
+// sequences to split and empty pages
+const modelPageSize = 4
+
+func newModelList(db *leveldb.DB) (*List, error) {
+	ls, err := NewList([]byte(testNamespace), db)
+	if err != nil {
+		return nil, err
+	}
+	ls.pageSize = modelPageSize
+	return ls, nil
+}
@@ -1,9 +1,13 @@
 package list
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"testing"
 
+	"github.com/lyonssp/leveladt"
 	"github.com/stretchr/testify/assert"
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -17,10 +21,8 @@ func TestList(t *testing.T) {
 	db, err := leveldb.OpenFile(dir, nil)
 	assert.Nil(err)
 
-	s := List{
-		ns:  []byte("xxx"),
-		ldb: db,
-	}
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
 
 	err = s.Append([]byte("foo"))
 	assert.Nil(err)
@@ -35,6 +37,9 @@ func TestList(t *testing.T) {
 	v, err = s.Get(1)
 	assert.Nil(err)
 	assert.Equal("bar", string(v))
+
+	_, err = s.Get(2)
+	assert.True(errors.Is(err, leveladt.ErrOutOfRange))
 }
 
 func TestNamespacing(t *testing.T) {
@@ -46,14 +51,42 @@ func TestNamespacing(t *testing.T) {
 	db, err := leveldb.OpenFile(dir, nil)
 	assert.Nil(err)
 
-	a := List{
-		ns:  []byte("xxx"),
-		ldb: db,
-	}
-	b := List{
-		ns:  []byte("yyy"),
-		ldb: db,
-	}
+	a, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	b, err := NewList([]byte("yyy"), db)
+	assert.Nil(err)
+
+	err = a.Append([]byte("foo"))
+	assert.Nil(err)
+
+	err = b.Append([]byte("bar"))
+	assert.Nil(err)
+
+	av, err := a.Get(0)
+	assert.Nil(err)
+	assert.Equal("foo", string(av))
+
+	bv, err := b.Get(0)
+	assert.Nil(err)
+	assert.Equal("bar", string(bv))
+}
+
+func TestNamespacingSharedSuffix(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// namespaces that differ only in their first byte
+	a, err := NewList([]byte("ax"), db)
+	assert.Nil(err)
+
+	b, err := NewList([]byte("bx"), db)
+	assert.Nil(err)
 
 	err = a.Append([]byte("foo"))
 	assert.Nil(err)
@@ -69,3 +102,260 @@ func TestNamespacing(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal("bar", string(bv))
 }
+
+func TestNamespacingSharedPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// namespaces that extend another with the prefixes of its pages
+	x, err := NewList([]byte("x"), db)
+	assert.Nil(err)
+
+	var want [][]byte
+	for i := 0; i < 3*defaultPageSize; i++ {
+		want = append(want, []byte(fmt.Sprint(i)))
+		assert.Nil(x.Append(want[i]))
+	}
+
+	// the length and layout keys of the last two have the length of page keys of x
+	for _, ns := range []string{"x" + pSize, "x" + pPage, "x" + pSize + "AB", "x" + pPage + "AB"} {
+		other, err := NewList([]byte(ns), db)
+		assert.Nil(err)
+		assert.Nil(other.Append([]byte(ns)))
+	}
+
+	x, err = NewList([]byte("x"), db)
+	assert.Nil(err)
+
+	got, err := x.Range(0, int64(len(want)))
+	assert.Nil(err)
+	assert.Equal(want, got)
+
+	it, err := x.ReverseIterator()
+	assert.Nil(err)
+	defer it.Release()
+
+	n := 0
+	for it.Next() {
+		assert.Equal(want[len(want)-1-n], it.Value())
+		n++
+	}
+	assert.Nil(it.Err())
+	assert.Equal(len(want), n)
+
+	assert.Nil(x.Append([]byte("last")))
+	v, err := x.Get(-1)
+	assert.Nil(err)
+	assert.Equal([]byte("last"), v)
+}
+
+func TestKeyOrdering(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	// enough items to need more than one byte of index
+	for i := 0; i < 300; i++ {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(i))
+		assert.Nil(s.Append(b))
+	}
+
+	iter := db.NewIterator(s.prefix(pPage), nil)
+	defer iter.Release()
+
+	i := uint64(0)
+	for iter.Next() {
+		items, err := decodePage(iter.Value())
+		assert.Nil(err)
+		for _, item := range items {
+			assert.Equal(i, binary.BigEndian.Uint64(item))
+			i++
+		}
+	}
+	assert.Nil(iter.Error())
+	assert.Equal(uint64(300), i)
+}
+
+func TestReopen(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	err = s.Append([]byte("foo"))
+	assert.Nil(err)
+
+	assert.Nil(db.Close())
+
+	db, err = leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err = NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	err = s.Append([]byte("bar"))
+	assert.Nil(err)
+
+	v, err := s.Get(0)
+	assert.Nil(err)
+	assert.Equal("foo", string(v))
+
+	v, err = s.Get(1)
+	assert.Nil(err)
+	assert.Equal("bar", string(v))
+}
+
+func TestMutation(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	_, err = s.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+	_, err = s.PopFront()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	assert.Nil(s.Append([]byte("b")))
+	assert.Nil(s.Insert(0, []byte("a")))
+	assert.Nil(s.Insert(2, []byte("d")))
+	assert.Nil(s.Insert(2, []byte("c")))
+	assert.Nil(s.Set(3, []byte("e")))
+
+	assert.True(errors.Is(s.Set(4, []byte("x")), leveladt.ErrOutOfRange))
+	assert.True(errors.Is(s.Insert(5, []byte("x")), leveladt.ErrOutOfRange))
+	assert.True(errors.Is(s.Delete(4), leveladt.ErrOutOfRange))
+
+	assert.Nil(s.Delete(1))
+
+	v, err := s.PopFront()
+	assert.Nil(err)
+	assert.Equal("a", string(v))
+
+	v, err = s.Pop()
+	assert.Nil(err)
+	assert.Equal("e", string(v))
+
+	v, err = s.Get(0)
+	assert.Nil(err)
+	assert.Equal("c", string(v))
+
+	_, err = s.Get(1)
+	assert.True(errors.Is(err, leveladt.ErrOutOfRange))
+}
+
+func TestNegativeIndices(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	for _, v := range []string{"a", "b", "c"} {
+		assert.Nil(s.Append([]byte(v)))
+	}
+
+	v, err := s.Get(-1)
+	assert.Nil(err)
+	assert.Equal("c", string(v))
+
+	v, err = s.Get(-3)
+	assert.Nil(err)
+	assert.Equal("a", string(v))
+
+	assert.Nil(s.Set(-2, []byte("B")))
+	assert.Nil(s.Insert(-1, []byte("x")))
+	assert.Nil(s.Delete(-4))
+
+	got, err := s.Range(-3, -1)
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("B"), []byte("x")}, got)
+
+	got, err = s.Range(-1, 3)
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("c")}, got)
+
+	// out of range lookups report the index as given and the length
+	_, err = s.Get(-4)
+	var ie *IndexError
+	if assert.True(errors.As(err, &ie)) {
+		assert.Equal(int64(-4), ie.Index)
+		assert.Equal(int64(3), ie.Length)
+	}
+	assert.True(errors.Is(err, leveladt.ErrOutOfRange))
+
+	_, err = s.Get(3)
+	assert.True(errors.As(err, &ie))
+	assert.True(errors.Is(s.Insert(-4, []byte("x")), leveladt.ErrOutOfRange))
+
+	// out of range lookups are answered without reading the database
+	assert.Nil(db.Close())
+	_, err = s.Get(5)
+	assert.True(errors.As(err, &ie))
+}
+
+func TestPageSplits(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err := NewList([]byte("xxx"), db)
+	assert.Nil(err)
+	s.pageSize = 2
+
+	// inserting at the front over and over halves the same gap until it is used up and
+	// the pages are renumbered
+	const n = 100
+	for i := 0; i < n; i++ {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(n-1-i))
+		assert.Nil(s.Insert(0, b))
+	}
+
+	assert.Nil(db.Close())
+	db, err = leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s, err = NewList([]byte("xxx"), db)
+	assert.Nil(err)
+
+	for i := int64(0); i < n; i++ {
+		v, err := s.Get(i)
+		assert.Nil(err)
+		assert.Equal(uint64(i), binary.BigEndian.Uint64(v))
+	}
+}
@@ -0,0 +1,159 @@
+package list
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// special key that marks a namespace as rewritten to the current key layout
+const pLayout = "layout"
+
+// key layout versions: the dense layout stores every item under its fixed-width index,
+// the paged layout stores items in pages, and the current layout stores the same pages
+// under a namespace preceded by its length
+const (
+	layoutDense   = 1
+	layoutPaged   = 2
+	layoutVersion = 3
+)
+
+// Migrate rewrites a list stored under namespace ns by an earlier release, whose keys
+// were a varint index written over the first bytes of the namespace, to the current
+// layout. Lists in the dense or paged layouts of later releases are rewritten too, as
+// they would be by NewList.
+//
+// Migrate is a one-time operation: it records the layout version under the namespace
+// and does nothing when called again. It must run before the list is opened with
+// NewList, and while no other process writes to the namespace. Since the legacy
+// layout did not isolate namespaces, lists sharing a database may have overwritten
+// each other's items before migration; those items cannot be recovered.
+func Migrate(ns []byte, ldb *leveldb.DB) error {
+	ls := &List{
+		ns:  ns,
+		ldb: ldb,
+	}
+
+	layout, err := ls.layout()
+	if err != nil {
+		return err
+	}
+	switch layout {
+	case layoutVersion:
+		return nil
+	case layoutDense, layoutPaged:
+		_, err := NewList(ns, ldb)
+		return err
+	}
+
+	// lists written before the length was persisted are read until the first missing index
+	length := int64(-1)
+	if v, err := ldb.Get(ls.plain(pLength), nil); err == nil {
+		if length, err = decodeLength(v); err != nil {
+			return err
+		}
+	} else if err != leveldb.ErrNotFound {
+		return fmt.Errorf("leveldb get: %w", err)
+	}
+
+	// legacy keys may collide with current keys, so every value is read before any key is rewritten
+	var values [][]byte
+	for i := int64(0); length < 0 || i < length; i++ {
+		v, err := ldb.Get(ls.legacyKey(i), nil)
+		if err == leveldb.ErrNotFound && length < 0 {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("leveldb get legacy index %d: %w", i, err)
+		}
+		values = append(values, v)
+	}
+
+	// legacy keys never collide with current keys, which differ in length
+	batch := new(leveldb.Batch)
+	for i := range values {
+		batch.Delete(ls.legacyKey(int64(i)))
+	}
+	batch.Delete(ls.plain(pLength))
+	ls.pageSize = defaultPageSize
+	ls.paginate(batch, values)
+	batch.Put(ls.pLength(), encodeLength(int64(len(values))))
+	batch.Put(ls.special(pLayout), encodeLength(layoutVersion))
+	if err := ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
+// legacyKey reproduces the key layout of earlier releases, where the varint encoded index
+// overwrote the leading bytes of the namespace
+func (ls *List) legacyKey(i int64) []byte {
+	namespaced := make([]byte, len(ls.ns)+8)
+	copy(namespaced[:len(ls.ns)], ls.ns)
+	binary.PutVarint(namespaced, i)
+	return namespaced
+}
+
+// migrateDense rewrites the items of a list in the dense layout to pages, in one batch
+func (ls *List) migrateDense() error {
+	values := make([][]byte, ls.length)
+	for i := range values {
+		v, err := ls.ldb.Get(ls.key(int64(i)), nil)
+		if err == leveldb.ErrNotFound {
+			return fmt.Errorf("no item at index %d of dense list, which may need Migrate: %w", i, leveladt.ErrCorrupt)
+		}
+		if err != nil {
+			return fmt.Errorf("leveldb get: %w", err)
+		}
+		values[i] = v
+	}
+
+	batch := new(leveldb.Batch)
+	for i := range values {
+		batch.Delete(ls.key(int64(i)))
+	}
+	batch.Delete(ls.plain(pLength))
+	batch.Delete(ls.plain(pLayout))
+	return ls.commit(batch, ls.paginate(batch, values), ls.length)
+}
+
+// migratePaged rewrites the pages of a list in the paged layout of layout version 2, whose
+// keys appended their names to the namespace directly, to the current layout, in one
+// batch. Pages of that layout are told apart from the keys of lists whose namespace
+// extends this one by length only, so such lists must be migrated first.
+func (ls *List) migratePaged() error {
+	length, err := ls.getLength(ls.plain(pLength))
+	if err != nil {
+		return err
+	}
+	pages, err := ls.readDirectory(ls.plain(pSize))
+	if err != nil {
+		return err
+	}
+
+	values := make([][]byte, len(pages))
+	for k, pg := range pages {
+		v, err := ls.ldb.Get(appendID(ls.plain(pPage), pg.id), nil)
+		if err == leveldb.ErrNotFound {
+			return fmt.Errorf("missing list page %d: %w", pg.id, leveladt.ErrCorrupt)
+		}
+		if err != nil {
+			return fmt.Errorf("leveldb get: %w", err)
+		}
+		values[k] = v
+	}
+
+	// keys of the two layouts differ in length, so none is both deleted and written
+	batch := new(leveldb.Batch)
+	for k, pg := range pages {
+		batch.Delete(appendID(ls.plain(pPage), pg.id))
+		batch.Delete(appendID(ls.plain(pSize), pg.id))
+		batch.Put(ls.page(pg.id), values[k])
+		batch.Put(ls.size(pg.id), encodeLength(int64(pg.n)))
+	}
+	batch.Delete(ls.plain(pLength))
+	batch.Delete(ls.plain(pLayout))
+	return ls.commit(batch, pages, length)
+}
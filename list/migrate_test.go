@@ -0,0 +1,170 @@
+package list
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestMigrate(t *testing.T) {
+	assert := assert.New(t)
+
+	// writeLegacy stores values the way earlier releases did, optionally without a persisted length
+	writeLegacy := func(db *leveldb.DB, ns []byte, withLength bool, values ...string) error {
+		ls := &List{ns: ns, ldb: db}
+		batch := new(leveldb.Batch)
+		for i, v := range values {
+			batch.Put(ls.legacyKey(int64(i)), []byte(v))
+		}
+		if withLength {
+			batch.Put(ls.plain(pLength), encodeLength(int64(len(values))))
+		}
+		return db.Write(batch, nil)
+	}
+
+	for _, withLength := range []bool{true, false} {
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		ns := []byte("test")
+		assert.Nil(writeLegacy(db, ns, withLength, "foo", "bar", "baz"))
+
+		assert.Nil(Migrate(ns, db))
+
+		// migrating twice must not reinterpret current keys as legacy keys
+		assert.Nil(Migrate(ns, db))
+
+		ls, err := NewList(ns, db)
+		assert.Nil(err)
+
+		assert.Nil(ls.Append([]byte("qux")))
+
+		for i, want := range []string{"foo", "bar", "baz", "qux"} {
+			v, err := ls.Get(int64(i))
+			assert.Nil(err)
+			assert.Equal(want, string(v))
+		}
+
+		_, err = db.Get(ls.legacyKey(0), nil)
+		assert.Equal(leveldb.ErrNotFound, err)
+	}
+}
+
+func TestMigrateCollidingKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	ns := []byte("test")
+	ls := &List{ns: ns, ldb: db}
+
+	// the legacy key of index 58 is byte-for-byte the current key of index 0
+	assert.Equal(ls.key(0), ls.legacyKey(58))
+
+	batch := new(leveldb.Batch)
+	for i := int64(0); i <= 58; i++ {
+		batch.Put(ls.legacyKey(i), encodeLength(i))
+	}
+	batch.Put(ls.plain(pLength), encodeLength(59))
+	assert.Nil(db.Write(batch, nil))
+
+	assert.Nil(Migrate(ns, db))
+
+	ls, err = NewList(ns, db)
+	assert.Nil(err)
+
+	for i := int64(0); i <= 58; i++ {
+		v, err := ls.Get(i)
+		assert.Nil(err)
+		assert.Equal(encodeLength(i), v)
+	}
+}
+
+func TestMigrateDense(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// write the dense layout of layout version 1
+	ns := []byte("test")
+	ls := &List{ns: ns, ldb: db}
+	batch := new(leveldb.Batch)
+	for i, v := range []string{"foo", "bar", "baz"} {
+		batch.Put(ls.key(int64(i)), []byte(v))
+	}
+	batch.Put(ls.plain(pLength), encodeLength(3))
+	batch.Put(ls.plain(pLayout), encodeLength(layoutDense))
+	assert.Nil(db.Write(batch, nil))
+
+	ls, err = NewList(ns, db)
+	assert.Nil(err)
+
+	assert.Nil(ls.Insert(1, []byte("qux")))
+	for i, want := range []string{"foo", "qux", "bar", "baz"} {
+		v, err := ls.Get(int64(i))
+		assert.Nil(err)
+		assert.Equal(want, string(v))
+	}
+
+	_, err = db.Get(ls.key(0), nil)
+	assert.Equal(leveldb.ErrNotFound, err)
+
+	layout, err := ls.layout()
+	assert.Nil(err)
+	assert.Equal(int64(layoutVersion), layout)
+}
+
+func TestMigratePaged(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// write the paged layout of layout version 2, with the namespace not length-prefixed
+	ns := []byte("test")
+	ls := &List{ns: ns, ldb: db}
+	batch := new(leveldb.Batch)
+	for k, items := range [][][]byte{{[]byte("foo"), []byte("bar")}, {[]byte("baz")}} {
+		id := uint64(k+1) * pageGap
+		batch.Put(appendID(ls.plain(pPage), id), encodePage(items))
+		batch.Put(appendID(ls.plain(pSize), id), encodeLength(int64(len(items))))
+	}
+	batch.Put(ls.plain(pLength), encodeLength(3))
+	batch.Put(ls.plain(pLayout), encodeLength(layoutPaged))
+	assert.Nil(db.Write(batch, nil))
+
+	assert.Nil(Migrate(ns, db))
+
+	ls, err = NewList(ns, db)
+	assert.Nil(err)
+
+	assert.Nil(ls.Insert(1, []byte("qux")))
+	got, err := ls.Range(0, 4)
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("foo"), []byte("qux"), []byte("bar"), []byte("baz")}, got)
+
+	for _, key := range [][]byte{ls.plain(pLength), ls.plain(pLayout), appendID(ls.plain(pPage), pageGap)} {
+		_, err = db.Get(key, nil)
+		assert.Equal(leveldb.ErrNotFound, err)
+	}
+
+	layout, err := ls.layout()
+	assert.Nil(err)
+	assert.Equal(int64(layoutVersion), layout)
+}
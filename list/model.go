@@ -1,9 +1,14 @@
 package list
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
 
 type listModel struct {
-	ls []string
+	ls         []string
+	lastPopped []byte // item removed by the last Delete, Pop or PopFront
 }
 
 func makeListModel() listModel {
@@ -18,12 +23,66 @@ func (mod *listModel) Append(x []byte) error {
 }
 
 func (mod listModel) Get(i int64) ([]byte, error) {
-	if i >= int64(len(mod.ls)) {
-		return nil, fmt.Errorf("no item found at index %d", i)
+	i, err := resolve("get", i, int64(len(mod.ls)), false)
+	if err != nil {
+		return nil, err
 	}
 	return []byte(mod.ls[i]), nil
 }
 
+func (mod *listModel) Set(i int64, x []byte) error {
+	i, err := resolve("set", i, int64(len(mod.ls)), false)
+	if err != nil {
+		return err
+	}
+	mod.ls[i] = string(x)
+	return nil
+}
+
+func (mod *listModel) Insert(i int64, x []byte) error {
+	i, err := resolve("insert at", i, int64(len(mod.ls)), true)
+	if err != nil {
+		return err
+	}
+	mod.ls = append(mod.ls, "")
+	copy(mod.ls[i+1:], mod.ls[i:])
+	mod.ls[i] = string(x)
+	return nil
+}
+
+func (mod *listModel) Delete(i int64) ([]byte, error) {
+	i, err := resolve("delete", i, int64(len(mod.ls)), false)
+	if err != nil {
+		return nil, err
+	}
+	x := mod.ls[i]
+	mod.ls = append(mod.ls[:i], mod.ls[i+1:]...)
+	mod.lastPopped = []byte(x)
+	return []byte(x), nil
+}
+
+func (mod *listModel) Pop() ([]byte, error) {
+	if len(mod.ls) == 0 {
+		return nil, fmt.Errorf("cannot pop from empty list: %w", leveladt.ErrEmpty)
+	}
+	return mod.Delete(int64(len(mod.ls) - 1))
+}
+
+func (mod *listModel) PopFront() ([]byte, error) {
+	if len(mod.ls) == 0 {
+		return nil, fmt.Errorf("cannot pop from empty list: %w", leveladt.ErrEmpty)
+	}
+	return mod.Delete(0)
+}
+
+func (mod listModel) Range(start, end int64) [][]byte {
+	vs := make([][]byte, 0, end-start)
+	for _, x := range mod.ls[start:end] {
+		vs = append(vs, []byte(x))
+	}
+	return vs
+}
+
 func (mod listModel) size() int {
 	return len(mod.ls)
 }
@@ -33,5 +92,6 @@ func (mod listModel) clone() listModel {
 	for _, x := range mod.ls {
 		cp.Append([]byte(x))
 	}
+	cp.lastPopped = mod.lastPopped
 	return cp
 }
@@ -0,0 +1,204 @@
+package list
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// prefixes of the keys that hold the pages of a list
+const (
+	pPage = "page" // followed by the big-endian ID of a page, holds its items
+	pSize = "size" // followed by the big-endian ID of a page, holds its number of items
+)
+
+// defaultPageSize is the maximum number of items in a page
+const defaultPageSize = 128
+
+// pageGap is the distance between the IDs of consecutive pages written in order. A page
+// split in two gives its second half the ID halfway to the next page, so the IDs of a
+// list are only rewritten once some gap is used up.
+const pageGap = 1 << 32
+
+// page is an entry of the page directory of a list
+type page struct {
+	id uint64
+	n  int // number of items in the page
+}
+
+// locate returns the position in the page directory of the page that holds index i, and
+// the offset of i in that page. The index must be in range.
+func (ls *List) locate(i int64) (int, int) {
+	for p, pg := range ls.pages {
+		if i < int64(pg.n) {
+			return p, int(i)
+		}
+		i -= int64(pg.n)
+	}
+	panic(fmt.Sprintf("list index %d out of range", i))
+}
+
+// splitID returns the ID for a new page following page p of pages. If there is no gap
+// left after page p, every page is first renumbered in batch.
+func (ls *List) splitID(batch *leveldb.Batch, pages []page, p int) (uint64, error) {
+	next := pages[p].id + 2*pageGap
+	if p+1 < len(pages) {
+		next = pages[p+1].id
+	}
+	if next-pages[p].id < 2 {
+		if err := ls.renumber(batch, pages); err != nil {
+			return 0, err
+		}
+		return pages[p].id + pageGap/2, nil
+	}
+	return pages[p].id + (next-pages[p].id)/2, nil
+}
+
+// renumber includes the move of every page of pages to evenly spaced IDs in batch, and
+// updates the IDs in pages
+func (ls *List) renumber(batch *leveldb.Batch, pages []page) error {
+	values := make([][]byte, len(pages))
+	for k, pg := range pages {
+		v, err := ls.ldb.Get(ls.page(pg.id), nil)
+		if err != nil {
+			return fmt.Errorf("leveldb get page %d: %w", pg.id, err)
+		}
+		values[k] = v
+	}
+
+	// batch operations apply in order, so old pages are deleted before any new ID is used
+	for _, pg := range pages {
+		ls.deletePage(batch, pg.id)
+	}
+	for k := range pages {
+		pages[k].id = uint64(k+1) * pageGap
+		batch.Put(ls.page(pages[k].id), values[k])
+		batch.Put(ls.size(pages[k].id), encodeLength(int64(pages[k].n)))
+	}
+	return nil
+}
+
+// paginate includes the writes of values as a new sequence of full pages in batch, and
+// returns their directory
+func (ls *List) paginate(batch *leveldb.Batch, values [][]byte) []page {
+	var pages []page
+	for start := 0; start < len(values); start += ls.pageSize {
+		end := start + ls.pageSize
+		if end > len(values) {
+			end = len(values)
+		}
+
+		id := uint64(len(pages)+1) * pageGap
+		ls.putPage(batch, id, values[start:end])
+		pages = append(pages, page{id: id, n: end - start})
+	}
+	return pages
+}
+
+// loadPages reads the page directory of the list
+func (ls *List) loadPages() error {
+	pages, err := ls.readDirectory(ls.special(pSize))
+	if err != nil {
+		return err
+	}
+	ls.pages = pages
+	return nil
+}
+
+// readDirectory reads the page directory from the keys that hold the sizes of the pages,
+// which follow prefix. Keys under prefix that are not followed by exactly a page ID are
+// skipped, since the paged layout of layout version 2 shared its prefixes with lists
+// whose namespace extends this one.
+func (ls *List) readDirectory(prefix []byte) ([]page, error) {
+	iter := ls.ldb.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	var pages []page
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != len(prefix)+8 {
+			continue
+		}
+		n, err := decodeLength(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page{id: binary.BigEndian.Uint64(key[len(prefix):]), n: int(n)})
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return pages, nil
+}
+
+// readPage reads the items of the page with the given ID
+func (ls *List) readPage(id uint64) ([][]byte, error) {
+	v, err := ls.ldb.Get(ls.page(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, fmt.Errorf("missing list page %d: %w", id, leveladt.ErrCorrupt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodePage(v)
+}
+
+// putPage includes the write of the page with the given ID and items in batch
+func (ls *List) putPage(batch *leveldb.Batch, id uint64, items [][]byte) {
+	batch.Put(ls.page(id), encodePage(items))
+	batch.Put(ls.size(id), encodeLength(int64(len(items))))
+}
+
+// deletePage includes the removal of the page with the given ID in batch
+func (ls *List) deletePage(batch *leveldb.Batch, id uint64) {
+	batch.Delete(ls.page(id))
+	batch.Delete(ls.size(id))
+}
+
+// page encodes the key that holds the items of the page with the given ID
+func (ls *List) page(id uint64) []byte {
+	return appendID(ls.special(pPage), id)
+}
+
+// size encodes the key that holds the number of items of the page with the given ID
+func (ls *List) size(id uint64) []byte {
+	return appendID(ls.special(pSize), id)
+}
+
+func appendID(b []byte, id uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], id)
+	return append(b, enc[:]...)
+}
+
+// encodePage encodes each item as a uvarint length followed by its raw bytes
+func encodePage(items [][]byte) []byte {
+	n := 0
+	for _, item := range items {
+		n += binary.MaxVarintLen64 + len(item)
+	}
+
+	b := make([]byte, 0, n)
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, item := range items {
+		b = append(b, lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(item)))]...)
+		b = append(b, item...)
+	}
+	return b
+}
+
+func decodePage(b []byte) ([][]byte, error) {
+	var items [][]byte
+	for len(b) > 0 {
+		n, k := binary.Uvarint(b)
+		if k <= 0 || uint64(len(b)-k) < n {
+			return nil, fmt.Errorf("malformed list page: %w", leveladt.ErrCorrupt)
+		}
+		items = append(items, b[k:k+int(n):k+int(n)])
+		b = b[k+int(n):]
+	}
+	return items, nil
+}
@@ -0,0 +1,118 @@
+package pqueue
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
+
+type pqModel struct {
+	items      modelHeap
+	nextSeq    uint64
+	lastPopped []byte
+}
+
+func makePQModel() pqModel {
+	return pqModel{items: make(modelHeap, 0)}
+}
+
+func (mod *pqModel) Push(priority int64, x []byte) (Handle, error) {
+	h := Handle(mod.nextSeq)
+	heap.Push(&mod.items, modelItem{priority: priority, seq: mod.nextSeq, val: string(x)})
+	mod.nextSeq++
+	return h, nil
+}
+
+func (mod *pqModel) Pop() ([]byte, error) {
+	if len(mod.items) <= 0 {
+		return nil, fmt.Errorf("priority queue is empty: %w", leveladt.ErrEmpty)
+	}
+
+	front := heap.Pop(&mod.items).(modelItem)
+	mod.lastPopped = []byte(front.val)
+	return []byte(front.val), nil
+}
+
+func (mod pqModel) Peek() ([]byte, error) {
+	if len(mod.items) <= 0 {
+		return nil, fmt.Errorf("priority queue is empty: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.items[0].val), nil
+}
+
+func (mod *pqModel) Remove(h Handle) error {
+	i, err := mod.find(h)
+	if err != nil {
+		return err
+	}
+	heap.Remove(&mod.items, i)
+	return nil
+}
+
+func (mod *pqModel) UpdatePriority(h Handle, p int64) error {
+	i, err := mod.find(h)
+	if err != nil {
+		return err
+	}
+	mod.items[i].priority = p
+	heap.Fix(&mod.items, i)
+	return nil
+}
+
+func (mod pqModel) find(h Handle) (int, error) {
+	for i, item := range mod.items {
+		if item.seq == uint64(h) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no item with handle %d: %w", h, leveladt.ErrNotFound)
+}
+
+// handles returns the handles of the items in the model, in no particular order
+func (mod pqModel) handles() []Handle {
+	hs := make([]Handle, len(mod.items))
+	for i, item := range mod.items {
+		hs[i] = Handle(item.seq)
+	}
+	return hs
+}
+
+func (mod pqModel) size() int {
+	return len(mod.items)
+}
+
+func (mod pqModel) clone() pqModel {
+	cp := make(modelHeap, len(mod.items))
+	copy(cp, mod.items)
+	return pqModel{items: cp, nextSeq: mod.nextSeq, lastPopped: mod.lastPopped}
+}
+
+type modelItem struct {
+	priority int64
+	seq      uint64
+	val      string
+}
+
+// modelHeap implements heap.Interface, ordering items by priority and then by push order
+type modelHeap []modelItem
+
+func (h modelHeap) Len() int { return len(h) }
+
+func (h modelHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority < h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h modelHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *modelHeap) Push(x interface{}) { *h = append(*h, x.(modelItem)) }
+
+func (h *modelHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
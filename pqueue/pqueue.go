@@ -0,0 +1,257 @@
+package pqueue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// special keys that hold the state of the priority queue, and the prefixes of the keys
+// that hold its items
+const (
+	pSeq    = "seq"    // sequence number the next pushed item will be assigned
+	pLen    = "len"    // number of items in the priority queue
+	pItem   = "item"   // followed by the big-endian priority and sequence number of an item
+	pHandle = "handle" // followed by the big-endian sequence number of an item, holds its priority
+)
+
+// Handle identifies an item pushed to a PriorityQueue
+type Handle uint64
+
+// PriorityQueue is a min-priority queue backed by LevelDB.
+//
+// Items are stored under their priority followed by their sequence number, encoded so
+// that LevelDB orders keys by priority and then in push order. The front of the queue
+// is therefore the first key of its namespace.
+type PriorityQueue struct {
+	ns  []byte
+	ldb *leveldb.DB
+	l   sync.Mutex
+}
+
+func NewPriorityQueue(ns []byte, ldb *leveldb.DB) *PriorityQueue {
+	return &PriorityQueue{
+		ns:  ns,
+		ldb: ldb,
+	}
+}
+
+// Push adds v to the queue with the given priority and returns the handle of the new item
+func (pq *PriorityQueue) Push(priority int64, v []byte) (Handle, error) {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	seq, err := pq.getUint(pq.special(pSeq))
+	if err != nil {
+		return 0, err
+	}
+	n, err := pq.getUint(pq.special(pLen))
+	if err != nil {
+		return 0, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(pq.item(priority, seq), v)
+	batch.Put(pq.handle(seq), encodeUint(encodePriority(priority)))
+	batch.Put(pq.special(pSeq), encodeUint(seq+1))
+	batch.Put(pq.special(pLen), encodeUint(n+1))
+	if err := pq.write(batch); err != nil {
+		return 0, err
+	}
+
+	return Handle(seq), nil
+}
+
+// Pop removes and returns the item with the lowest priority. Items of equal priority are
+// popped in the order they were pushed.
+func (pq *PriorityQueue) Pop() ([]byte, error) {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	key, v, err := pq.front()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pq.remove(key); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Peek returns the item Pop would return, without removing it
+func (pq *PriorityQueue) Peek() ([]byte, error) {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	_, v, err := pq.front()
+	return v, err
+}
+
+// Len returns the number of items in the queue
+func (pq *PriorityQueue) Len() (int64, error) {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	n, err := pq.getUint(pq.special(pLen))
+	return int64(n), err
+}
+
+// Remove deletes the item with handle h. Remove returns an error wrapping
+// leveladt.ErrNotFound if the item is no longer in the queue.
+func (pq *PriorityQueue) Remove(h Handle) error {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	priority, err := pq.priority(h)
+	if err != nil {
+		return err
+	}
+	return pq.remove(pq.item(priority, uint64(h)))
+}
+
+// UpdatePriority changes the priority of the item with handle h to p. Among items of
+// equal priority, the item keeps the position of its original push. UpdatePriority
+// returns an error wrapping leveladt.ErrNotFound if the item is no longer in the queue.
+func (pq *PriorityQueue) UpdatePriority(h Handle, p int64) error {
+	pq.l.Lock()
+	defer pq.l.Unlock()
+
+	priority, err := pq.priority(h)
+	if err != nil {
+		return err
+	}
+
+	old := pq.item(priority, uint64(h))
+	v, err := pq.ldb.Get(old, nil)
+	if err != nil {
+		return fmt.Errorf("leveldb get: %w", err)
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(old)
+	batch.Put(pq.item(p, uint64(h)), v)
+	batch.Put(pq.handle(uint64(h)), encodeUint(encodePriority(p)))
+	return pq.write(batch)
+}
+
+// front returns the key and value of the item at the front of the queue. Namespaces are
+// not delimited, so keys of priority queues whose namespace extends this one share the
+// prefix of its items, and are told apart by their length.
+func (pq *PriorityQueue) front() ([]byte, []byte, error) {
+	prefix := pq.special(pItem)
+	iter := pq.ldb.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if len(iter.Key()) == len(prefix)+16 {
+			return append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...), nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return nil, nil, fmt.Errorf("priority queue is empty: %w", leveladt.ErrEmpty)
+}
+
+// remove deletes the item stored under key, along with its handle, and decrements the length
+func (pq *PriorityQueue) remove(key []byte) error {
+	n, err := pq.getUint(pq.special(pLen))
+	if err != nil {
+		return err
+	}
+	seq := binary.BigEndian.Uint64(key[len(key)-8:])
+
+	batch := new(leveldb.Batch)
+	batch.Delete(key)
+	batch.Delete(pq.handle(seq))
+	batch.Put(pq.special(pLen), encodeUint(n-1))
+	return pq.write(batch)
+}
+
+// priority returns the priority of the item with handle h
+func (pq *PriorityQueue) priority(h Handle) (int64, error) {
+	v, err := pq.ldb.Get(pq.handle(uint64(h)), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, fmt.Errorf("no item with handle %d: %w", h, leveladt.ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+
+	u, err := decodeUint(v)
+	if err != nil {
+		return 0, err
+	}
+	return decodePriority(u), nil
+}
+
+/*
+  convenience accessors that respect the priority queue namespace
+*/
+
+// getUint reads the integer stored under key, which is zero if the key was never written
+func (pq *PriorityQueue) getUint(key []byte) (uint64, error) {
+	v, err := pq.ldb.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodeUint(v)
+}
+
+func (pq *PriorityQueue) write(batch *leveldb.Batch) error {
+	if err := pq.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
+// special encodes a special key or prefix, respecting the namespace of the priority queue
+func (pq *PriorityQueue) special(name string) []byte {
+	namespaced := make([]byte, len(pq.ns)+len(name))
+	copy(namespaced, pq.ns)
+	copy(namespaced[len(pq.ns):], name)
+	return namespaced
+}
+
+// item encodes the key of the item with the given priority and sequence number
+func (pq *PriorityQueue) item(priority int64, seq uint64) []byte {
+	key := pq.special(pItem)
+	key = append(key, encodeUint(encodePriority(priority))...)
+	return append(key, encodeUint(seq)...)
+}
+
+// handle encodes the key that maps the sequence number of an item to its priority
+func (pq *PriorityQueue) handle(seq uint64) []byte {
+	return append(pq.special(pHandle), encodeUint(seq)...)
+}
+
+// encodePriority maps priority to an unsigned integer with the same ordering, by flipping the sign bit
+func encodePriority(priority int64) uint64 {
+	return uint64(priority) ^ (1 << 63)
+}
+
+func decodePriority(u uint64) int64 {
+	return int64(u ^ (1 << 63))
+}
+
+// encodeUint returns the big-endian encoding of u, which sorts in numeric order
+func encodeUint(u uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, u)
+	return b
+}
+
+func decodeUint(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("malformed integer of length %d: %w", len(b), leveladt.ErrCorrupt)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
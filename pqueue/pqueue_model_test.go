@@ -0,0 +1,411 @@
+package pqueue
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/commands"
+	"github.com/leanovate/gopter/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const testNamespace = "test"
+
+func TestPriorityQueueModel(t *testing.T) {
+	assert := assert.New(t)
+
+	test := &commands.ProtoCommands{
+		NewSystemUnderTestFunc: func(initialState commands.State) commands.SystemUnderTest {
+			dir, err := ioutil.TempDir("", "pqueue-*")
+			assert.Nil(err)
+
+			db, err := leveldb.OpenFile(dir, nil)
+			assert.Nil(err)
+
+			return &pqController{
+				dir:    dir,
+				ldb:    db,
+				pqueue: NewPriorityQueue([]byte(testNamespace), db),
+			}
+		},
+		InitialStateGen: gen.Const(makePQModel()),
+		InitialPreConditionFunc: func(_ commands.State) bool {
+			return true
+		},
+		GenCommandFunc: func(st commands.State) gopter.Gen {
+			return gen.OneGenOf(
+				genPushCommand,
+				genPopCommand,
+				genPeekCommand,
+				genLenCommand,
+				genRemoveCommand(st),
+				genUpdatePriorityCommand(st),
+				genCrashCommand,
+			)
+		},
+	}
+
+	properties := gopter.NewProperties(gopter.DefaultTestParameters())
+	properties.Property("model", commands.Prop(test))
+	properties.TestingRun(t)
+}
+
+// genPriority generates priorities from a small range so that ties are common
+var genPriority gopter.Gen = gen.Int64Range(-3, 3)
+
+// genBytes generates arbitrary byte slices
+var genBytes gopter.Gen = gen.SliceOf(gen.UInt8())
+
+func genPushCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		pushCommand{
+			priority: genPriority(params).Result.(int64),
+			x:        genBytes(params).Result.([]byte),
+		},
+		gopter.NoShrinker,
+	)
+}
+
+func genPopCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genPeekCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		peekCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genLenCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		lenCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+var genRemoveCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		hs := st.(pqModel).handles()
+		if len(hs) == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(removeCommand{}))
+		}
+		return gopter.NewGenResult(
+			removeCommand{h: hs[params.Rng.Intn(len(hs))]},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genUpdatePriorityCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		hs := st.(pqModel).handles()
+		if len(hs) == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(updatePriorityCommand{}))
+		}
+		return gopter.NewGenResult(
+			updatePriorityCommand{
+				h:        hs[params.Rng.Intn(len(hs))],
+				priority: genPriority(params).Result.(int64),
+			},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+func genCrashCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		crashCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+type pushCommand struct {
+	priority int64
+	x        []byte
+}
+
+func (cmd pushCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	h, err := pq.Push(cmd.priority, cmd.x)
+	if err != nil {
+		return commands.Result(err)
+	}
+	return h
+}
+
+func (cmd pushCommand) NextState(state commands.State) commands.State {
+	st := state.(pqModel).clone()
+	st.Push(cmd.priority, cmd.x)
+	return st
+}
+
+func (cmd pushCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd pushCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	// the model assigns handles in push order, just like the system
+	got := result.(Handle)
+	want := Handle(st.(pqModel).nextSeq - 1)
+	if got != want {
+		return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd pushCommand) String() string {
+	return fmt.Sprintf("push(%d, %q)", cmd.priority, cmd.x)
+}
+
+type popCommand struct{}
+
+func (cmd popCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	front, err := pq.Pop()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return front
+}
+
+func (cmd popCommand) NextState(state commands.State) commands.State {
+	st := state.(pqModel).clone()
+	st.Pop()
+	return st
+}
+
+func (cmd popCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(pqModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popCommand) PreCondition(st commands.State) bool {
+	return st.(pqModel).size() > 0
+}
+
+func (cmd popCommand) String() string {
+	return "pop()"
+}
+
+type peekCommand struct{}
+
+func (cmd peekCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	front, err := pq.Peek()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return front
+}
+
+func (cmd peekCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd peekCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want, _ := st.(pqModel).Peek()
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd peekCommand) PreCondition(st commands.State) bool {
+	return st.(pqModel).size() > 0
+}
+
+func (cmd peekCommand) String() string {
+	return "peek()"
+}
+
+type lenCommand struct{}
+
+func (cmd lenCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	n, err := pq.Len()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return n
+}
+
+func (cmd lenCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd lenCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.(int64)
+	want := int64(st.(pqModel).size())
+	if got != want {
+		return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd lenCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd lenCommand) String() string {
+	return "len()"
+}
+
+type removeCommand struct {
+	h Handle
+}
+
+func (cmd removeCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	if err := pq.Remove(cmd.h); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd removeCommand) NextState(state commands.State) commands.State {
+	st := state.(pqModel).clone()
+	st.Remove(cmd.h)
+	return st
+}
+
+func (cmd removeCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd removeCommand) PreCondition(st commands.State) bool {
+	_, err := st.(pqModel).find(cmd.h)
+	return err == nil
+}
+
+func (cmd removeCommand) String() string {
+	return fmt.Sprintf("remove(%d)", cmd.h)
+}
+
+type updatePriorityCommand struct {
+	h        Handle
+	priority int64
+}
+
+func (cmd updatePriorityCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pq := sut.(*pqController).pqueue
+	if err := pq.UpdatePriority(cmd.h, cmd.priority); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd updatePriorityCommand) NextState(state commands.State) commands.State {
+	st := state.(pqModel).clone()
+	st.UpdatePriority(cmd.h, cmd.priority)
+	return st
+}
+
+func (cmd updatePriorityCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd updatePriorityCommand) PreCondition(st commands.State) bool {
+	_, err := st.(pqModel).find(cmd.h)
+	return err == nil
+}
+
+func (cmd updatePriorityCommand) String() string {
+	return fmt.Sprintf("updatePriority(%d, %d)", cmd.h, cmd.priority)
+}
+
+type crashCommand struct{}
+
+func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	pc := sut.(*pqController)
+
+	// close LevelDB connection and release resources
+	pc.ldb.Close()
+
+	// create new LevelDB connection
+	db, err := leveldb.OpenFile(pc.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	pc.ldb = db
+	pc.pqueue = NewPriorityQueue([]byte(testNamespace), db)
+
+	return nil
+}
+
+func (cmd crashCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd crashCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd crashCommand) PreCondition(st commands.State) bool {
+	return true
+}
+
+func (cmd crashCommand) String() string {
+	return "crash()"
+}
+
+var (
+	_ commands.Command = pushCommand{}
+	_ commands.Command = popCommand{}
+	_ commands.Command = peekCommand{}
+	_ commands.Command = lenCommand{}
+	_ commands.Command = removeCommand{}
+	_ commands.Command = updatePriorityCommand{}
+	_ commands.Command = crashCommand{}
+)
+
+// pqController preserves the underlying reference to resources consumed by a
+// PriorityQueue to enable commands that represent restarts, filesystem failures, etc.
+type pqController struct {
+	dir    string         // root of LevelDB database
+	ldb    *leveldb.DB    // current LevelDB connection
+	pqueue *PriorityQueue // priority queue under test
+}
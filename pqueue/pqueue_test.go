@@ -0,0 +1,160 @@
+package pqueue
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	pq := NewPriorityQueue([]byte("test"), db)
+
+	_, err = pq.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	_, err = pq.Push(2, []byte("two"))
+	assert.Nil(err)
+	_, err = pq.Push(math.MinInt64, []byte("min"))
+	assert.Nil(err)
+	_, err = pq.Push(-1, []byte("minus one"))
+	assert.Nil(err)
+	_, err = pq.Push(2, []byte("two again"))
+	assert.Nil(err)
+	_, err = pq.Push(math.MaxInt64, []byte("max"))
+	assert.Nil(err)
+
+	n, err := pq.Len()
+	assert.Nil(err)
+	assert.Equal(int64(5), n)
+
+	front, err := pq.Peek()
+	assert.Nil(err)
+	assert.Equal([]byte("min"), front)
+
+	for _, want := range []string{"min", "minus one", "two", "two again", "max"} {
+		got, err := pq.Pop()
+		assert.Nil(err)
+		assert.Equal([]byte(want), got)
+	}
+
+	n, err = pq.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+}
+
+func TestHandles(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	pq := NewPriorityQueue([]byte("test"), db)
+
+	foo, err := pq.Push(1, []byte("foo"))
+	assert.Nil(err)
+	bar, err := pq.Push(2, []byte("bar"))
+	assert.Nil(err)
+	baz, err := pq.Push(3, []byte("baz"))
+	assert.Nil(err)
+
+	assert.Nil(pq.UpdatePriority(baz, 0))
+	assert.Nil(pq.Remove(foo))
+
+	err = pq.Remove(foo)
+	assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+	err = pq.UpdatePriority(foo, 0)
+	assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+	got, err := pq.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("baz"), got)
+
+	// handles of popped items are no longer valid
+	err = pq.Remove(baz)
+	assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+	assert.Nil(pq.Remove(bar))
+
+	n, err := pq.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+}
+
+func TestNamespacing(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	a := NewPriorityQueue([]byte("xxx"), db)
+	b := NewPriorityQueue([]byte("yyy"), db)
+
+	_, err = a.Push(1, []byte("foo"))
+	assert.Nil(err)
+
+	_, err = b.Push(0, []byte("bar"))
+	assert.Nil(err)
+
+	front, err := a.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("foo"), front)
+
+	front, err = b.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("bar"), front)
+}
+
+func TestNamespacingSharedPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// a namespace that extends another with the prefix of its items
+	a := NewPriorityQueue([]byte("jobs"), db)
+	b := NewPriorityQueue([]byte("jobsitems"), db)
+
+	_, err = a.Push(1, []byte("p1"))
+	assert.Nil(err)
+
+	_, err = b.Push(1, []byte("q1"))
+	assert.Nil(err)
+
+	front, err := a.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("p1"), front)
+
+	_, err = a.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	n, err := b.Len()
+	assert.Nil(err)
+	assert.Equal(int64(1), n)
+
+	front, err = b.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("q1"), front)
+}
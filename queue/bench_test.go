@@ -0,0 +1,234 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// payload sizes exercised by every benchmark
+var benchSizes = []int{16, 1 << 10, 64 << 10}
+
+// benchQueue is the interface shared by Queue and the linked list layout it replaced
+type benchQueue interface {
+	Enqueue(v []byte, opts ...EnqueueOption) error
+	Dequeue() ([]byte, error)
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("seq/%d", size), func(b *testing.B) {
+			benchmarkEnqueue(b, size, func(db *leveldb.DB) benchQueue { return NewQueue([]byte("bench"), db) })
+		})
+		b.Run(fmt.Sprintf("linked/%d", size), func(b *testing.B) {
+			benchmarkEnqueue(b, size, func(db *leveldb.DB) benchQueue { return newLinkedQueue([]byte("bench"), db) })
+		})
+	}
+}
+
+func BenchmarkDequeue(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("seq/%d", size), func(b *testing.B) {
+			benchmarkDequeue(b, size, func(db *leveldb.DB) benchQueue { return NewQueue([]byte("bench"), db) })
+		})
+		b.Run(fmt.Sprintf("linked/%d", size), func(b *testing.B) {
+			benchmarkDequeue(b, size, func(db *leveldb.DB) benchQueue { return newLinkedQueue([]byte("bench"), db) })
+		})
+	}
+}
+
+func benchmarkEnqueue(b *testing.B, size int, newQueue func(*leveldb.DB) benchQueue) {
+	dir, db := openBenchDB(b)
+	q := newQueue(db)
+	v := bytes.Repeat([]byte{'x'}, size)
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := q.Enqueue(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	reportDiskUsage(b, dir, db)
+}
+
+func benchmarkDequeue(b *testing.B, size int, newQueue func(*leveldb.DB) benchQueue) {
+	_, db := openBenchDB(b)
+	q := newQueue(db)
+	v := bytes.Repeat([]byte{'x'}, size)
+
+	for i := 0; i < b.N; i++ {
+		if err := q.Enqueue(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := q.Dequeue(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func openBenchDB(b *testing.B) (string, *leveldb.DB) {
+	dir, err := ioutil.TempDir("", "bench-*")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return dir, db
+}
+
+// reportDiskUsage compacts the database and reports its size on disk per enqueued item
+func reportDiskUsage(b *testing.B, dir string, db *leveldb.DB) {
+	if err := db.CompactRange(util.Range{}); err != nil {
+		b.Fatal(err)
+	}
+
+	tables, err := filepath.Glob(filepath.Join(dir, "*.ldb"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var total int64
+	for _, table := range tables {
+		info, err := os.Stat(table)
+		if err != nil {
+			b.Fatal(err)
+		}
+		total += info.Size()
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "disk-B/op")
+}
+
+// linkedQueue is the layout Queue used before sequence numbered keys, kept for comparison
+// and to write queues for Migrate to upgrade. Each encoded item is the key of the item
+// after it, and the front and back pointers hold full encoded items.
+type linkedQueue struct {
+	ns  []byte
+	ldb *leveldb.DB
+	l   sync.Mutex
+}
+
+func newLinkedQueue(ns []byte, ldb *leveldb.DB) *linkedQueue {
+	return &linkedQueue{
+		ns:  ns,
+		ldb: ldb,
+	}
+}
+
+// Enqueue ignores opts, since the linked list layout never supported them
+func (ls *linkedQueue) Enqueue(v []byte, opts ...EnqueueOption) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	encoded, err := encodeLinked(linkedItem{ns: string(ls.ns), val: string(v), nonce: uuid.NewString()})
+	if err != nil {
+		return err
+	}
+
+	encBack, err := ls.get(ls.pointer(pBack))
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	if encBack == nil {
+		batch.Put(ls.pointer(pFront), encoded)
+	}
+	batch.Put(encBack, encoded)
+	batch.Put(ls.pointer(pBack), encoded)
+	return ls.ldb.Write(batch, nil)
+}
+
+func (ls *linkedQueue) Dequeue() ([]byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	encFront, err := ls.get(ls.pointer(pFront))
+	if err != nil {
+		return nil, err
+	}
+	if encFront == nil {
+		return nil, errors.New("cannot pop from empty queue")
+	}
+
+	newEncFront, err := ls.get(encFront)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(encFront)
+	if newEncFront != nil {
+		batch.Put(ls.pointer(pFront), newEncFront)
+	} else {
+		batch.Put(ls.pointer(pBack), ls.pointer(pFront))
+	}
+
+	if err := ls.ldb.Write(batch, nil); err != nil {
+		return nil, err
+	}
+
+	val, err := (&Queue{ns: ls.ns}).decodeLinked(encFront)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(val), nil
+}
+
+func (ls *linkedQueue) get(key []byte) ([]byte, error) {
+	v, err := ls.ldb.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (ls *linkedQueue) pointer(name string) []byte {
+	var b bytes.Buffer
+	fmt.Fprint(&b, string(ls.ns), name)
+	return b.Bytes()
+}
+
+// linkedItem is an item as the linked list layout encoded it
+type linkedItem struct {
+	ns    string
+	val   string
+	nonce string
+}
+
+func (li linkedItem) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s %s", li.ns, li.val, li.nonce)
+	return b.Bytes(), nil
+}
+
+func encodeLinked(li linkedItem) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(li); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// OverflowPolicy decides what an enqueue does when a bounded queue is at capacity
+type OverflowPolicy int
+
+const (
+	// OverflowReject fails the enqueue with an error wrapping leveladt.ErrFull
+	OverflowReject OverflowPolicy = iota
+
+	// OverflowBlock blocks the enqueue until enough items are removed from the queue
+	OverflowBlock
+
+	// OverflowDropOldest discards items from the front of the queue, in the same batch as
+	// the enqueue, until the new items fit
+	OverflowDropOldest
+)
+
+// EnqueueWait enqueues v to the back of the queue like Enqueue. Under OverflowBlock, it
+// gives up once ctx is done and returns the error of ctx.
+func (ls *Queue) EnqueueWait(ctx context.Context, v []byte, opts ...EnqueueOption) error {
+	return ls.admit(ctx, 1, uint64(len(v)), func() error {
+		return ls.enqueueAt(new(leveldb.Batch), ls.now(), v, opts)
+	})
+}
+
+// admit calls enqueue, which adds n items of the given total size, with the queue lock
+// held. Under OverflowBlock, admit calls enqueue again every time items are removed, for
+// as long as it fails because the queue is full and ctx is not done.
+func (ls *Queue) admit(ctx context.Context, n, size uint64, enqueue func() error) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	for {
+		// due items were made visible before the items enqueued now, so they go first
+		if err := ls.promote(); err != nil {
+			return err
+		}
+
+		err := enqueue()
+		if ls.o.Overflow != OverflowBlock || !errors.Is(err, leveladt.ErrFull) || ls.oversized(n, size) {
+			return err
+		}
+
+		room := ls.roomChanged()
+		ls.l.Unlock()
+
+		select {
+		case <-room:
+			ls.l.Lock()
+		case <-ctx.Done():
+			ls.l.Lock()
+			return ctx.Err()
+		}
+	}
+}
+
+// makeRoom checks that n items of the given total size fit in the queue delimited by b.
+// Under OverflowDropOldest, it includes the removal of as many items from the front of
+// the queue as needed in batch, and returns the expired ones among them for commit.
+func (ls *Queue) makeRoom(batch *leveldb.Batch, b *bounds, n, size uint64) ([]queueValue, error) {
+	if ls.fits(*b, n, size) {
+		return nil, nil
+	}
+	if ls.oversized(n, size) || ls.o.Overflow != OverflowDropOldest {
+		return nil, fmt.Errorf("cannot enqueue %d items of %d bytes: %w", n, size, leveladt.ErrFull)
+	}
+
+	var expired []queueValue
+	for !ls.fits(*b, n, size) {
+		dropped, e, err := ls.pop(batch, b, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(dropped)+len(e) == 0 {
+			return nil, fmt.Errorf("queue holds fewer items than counted: %w", leveladt.ErrCorrupt)
+		}
+		expired = append(expired, e...)
+	}
+	return expired, nil
+}
+
+// fits returns true if n items of the given total size can be added to the queue
+// delimited by b without exceeding its capacity
+func (ls *Queue) fits(b bounds, n, size uint64) bool {
+	if ls.o.MaxLen > 0 && b.count+n > uint64(ls.o.MaxLen) {
+		return false
+	}
+	if ls.o.MaxBytes > 0 && b.bytes+size > uint64(ls.o.MaxBytes) {
+		return false
+	}
+	return true
+}
+
+// oversized returns true if n items of the given total size exceed the capacity of the
+// queue even when it is empty
+func (ls *Queue) oversized(n, size uint64) bool {
+	return !ls.fits(bounds{}, n, size)
+}
+
+// roomChanged returns a channel that is closed the next time items are removed from the
+// queue. The caller must hold the queue lock.
+func (ls *Queue) roomChanged() <-chan struct{} {
+	if ls.roomCh == nil {
+		ls.roomCh = make(chan struct{})
+	}
+	return ls.roomCh
+}
+
+// freed wakes the producers waiting for room in the queue. The caller must hold the queue
+// lock.
+func (ls *Queue) freed() {
+	if ls.roomCh != nil {
+		close(ls.roomCh)
+		ls.roomCh = nil
+	}
+}
@@ -0,0 +1,201 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func openBoundedQueue(t *testing.T, o Options) *Queue {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewQueueWithOptions([]byte("test"), db, &o)
+}
+
+func TestBounded(t *testing.T) {
+	t.Run("reject by length", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 2})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		err := q.Enqueue([]byte("baz"))
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		_, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Nil(q.Enqueue([]byte("baz")))
+	})
+
+	t.Run("reject by size", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxBytes: 6})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		err := q.Enqueue([]byte("barbaz"))
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		assert.Nil(q.Enqueue([]byte("bar")))
+	})
+
+	t.Run("reject whole batch", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 2})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		err := q.EnqueueBatch([][]byte{[]byte("bar"), []byte("baz")})
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 2, Overflow: OverflowDropOldest})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("baz"), []byte("qux")}))
+
+		got, err := q.DequeueBatch(10)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("baz"), []byte("qux")}, got)
+	})
+
+	t.Run("drop oldest by size", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxBytes: 8, Overflow: OverflowDropOldest})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+		assert.Nil(q.Enqueue([]byte("bazqux")))
+
+		got, err := q.DequeueBatch(10)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("bazqux")}, got)
+	})
+
+	t.Run("oversized item", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxBytes: 2, Overflow: OverflowBlock})
+
+		err := q.Enqueue([]byte("foo"))
+		assert.True(errors.Is(err, leveladt.ErrFull))
+	})
+
+	t.Run("block until room", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 1, Overflow: OverflowBlock})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		done := make(chan error)
+		go func() {
+			done <- q.Enqueue([]byte("bar"))
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("enqueue did not block on a full queue")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+
+		select {
+		case err := <-done:
+			assert.Nil(err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("producer was not woken when room was made")
+		}
+
+		got, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+	})
+
+	t.Run("block with deadline", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 1, Overflow: OverflowBlock})
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		err := q.EnqueueWait(ctx, []byte("bar"))
+		assert.True(errors.Is(err, context.DeadlineExceeded))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+	})
+
+	t.Run("scheduled items are admitted when due", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openBoundedQueue(t, Options{MaxLen: 1})
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("bar")))
+
+		clock.Advance(time.Minute)
+
+		// the due item is moved in past the limit before the new item is refused
+		err := q.Enqueue([]byte("baz"))
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), n)
+	})
+
+	t.Run("limits survive restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		o := &Options{MaxBytes: 6}
+		q := NewQueueWithOptions([]byte("test"), db, o)
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q = NewQueueWithOptions([]byte("test"), db, o)
+		err = q.Enqueue([]byte("baz"))
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		_, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Nil(q.Enqueue([]byte("baz")))
+	})
+}
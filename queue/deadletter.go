@@ -0,0 +1,199 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// suffix of the namespace of the dead-letter queue of a queue. The leading zero byte keeps
+// it apart from the namespaces of other queues, such as a queue named "jobsdead" next to
+// a queue named "jobs".
+const pDead = "\x00dead"
+
+// DeadLetter is an item moved to the dead-letter queue after its last allowed delivery failed
+type DeadLetter struct {
+	Seq       uint64 // sequence number of the item in the dead-letter queue
+	Value     []byte // item as it was enqueued
+	Attempts  int    // number of times the item was delivered
+	LastError string // reason the last delivery failed
+}
+
+// DeadLetters returns the dead-letter queue, which holds items whose last allowed delivery
+// failed. It is stored in the same LevelDB as the queue, under the queue namespace
+// followed by a zero byte and "dead".
+func (ls *Queue) DeadLetters() *Queue {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	return ls.deadLetters()
+}
+
+// ListDeadLetters returns up to limit items from the front of the dead-letter queue,
+// without removing them. A limit of zero or less lists every dead letter.
+func (ls *Queue) ListDeadLetters(limit int) ([]DeadLetter, error) {
+	dlq := ls.DeadLetters()
+	dlq.l.Lock()
+	defer dlq.l.Unlock()
+
+	b, err := dlq.bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	n := b.size()
+	if limit > 0 && uint64(limit) < n {
+		n = uint64(limit)
+	}
+
+	iter := dlq.ldb.NewIterator(&util.Range{Start: dlq.item(b.front), Limit: dlq.item(b.back)}, nil)
+	defer iter.Release()
+
+	dls := make([]DeadLetter, 0, n)
+	for uint64(len(dls)) < n && iter.Next() {
+		dl, err := decodeDeadLetter(dlq.seq(iter.Key()), iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		dls = append(dls, dl)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return dls, nil
+}
+
+// InspectDeadLetter returns the dead letter with sequence number seq without removing it.
+// InspectDeadLetter returns an error wrapping leveladt.ErrNotFound if there is none.
+func (ls *Queue) InspectDeadLetter(seq uint64) (DeadLetter, error) {
+	dlq := ls.DeadLetters()
+	dlq.l.Lock()
+	defer dlq.l.Unlock()
+
+	return dlq.deadLetter(seq)
+}
+
+// Redrive moves up to n items from the front of the dead-letter queue back to the back of
+// the queue in a single batch, resetting their delivery attempts. An n of zero or less
+// moves every dead letter. Redrive returns the number of items moved.
+func (ls *Queue) Redrive(n int) (int, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	dlq := ls.deadLetters()
+	dlq.l.Lock()
+	defer dlq.l.Unlock()
+
+	b, err := ls.bounds()
+	if err != nil {
+		return 0, err
+	}
+	db, err := dlq.bounds()
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 || uint64(n) > db.size() {
+		n = int(db.size())
+	}
+
+	// dead letters never expire, since their expiry is cleared when they are moved
+	batch := new(leveldb.Batch)
+	qvs, _, err := dlq.pop(batch, &db, n)
+	if err != nil {
+		return 0, err
+	}
+	for _, qv := range qvs {
+		encoded, err := encode(queueValue{val: qv.val})
+		if err != nil {
+			return 0, err
+		}
+		ls.push(batch, &b, encoded)
+	}
+	dlq.putBounds(batch, db)
+	ls.putBounds(batch, b)
+
+	if err := ls.write(batch); err != nil {
+		return 0, err
+	}
+
+	ls.notify(len(qvs))
+	return len(qvs), nil
+}
+
+// PurgeDeadLetters removes every item from the dead-letter queue and returns the number of
+// items removed. Like Purge, it works in batches of bounded size.
+func (ls *Queue) PurgeDeadLetters() (int, error) {
+	return ls.DeadLetters().purge()
+}
+
+// kill includes the move of qvs to the back of the dead-letter queue in batch and writes
+// the batch. Dead letters are kept until they are removed, whatever their time to live.
+// The caller must hold the queue lock.
+func (ls *Queue) kill(batch *leveldb.Batch, qvs ...queueValue) error {
+	dlq := ls.deadLetters()
+	dlq.l.Lock()
+	defer dlq.l.Unlock()
+
+	b, err := dlq.bounds()
+	if err != nil {
+		return err
+	}
+
+	for _, qv := range qvs {
+		qv.expires = 0
+		encoded, err := encode(qv)
+		if err != nil {
+			return err
+		}
+		dlq.push(batch, &b, encoded)
+	}
+	dlq.putBounds(batch, b)
+
+	if err := dlq.write(batch); err != nil {
+		return err
+	}
+
+	dlq.notify(len(qvs))
+	return nil
+}
+
+// deadLetters returns the dead-letter queue, creating it on first use. The caller must
+// hold the queue lock.
+func (ls *Queue) deadLetters() *Queue {
+	if ls.dlq == nil {
+		ns := make([]byte, 0, len(ls.ns)+len(pDead))
+		ns = append(append(ns, ls.ns...), pDead...)
+		ls.dlq = NewQueue(ns, ls.ldb)
+		ls.dlq.now = ls.now
+	}
+	return ls.dlq
+}
+
+// deadLetter reads the dead letter with sequence number seq. The caller must hold the
+// lock of the dead-letter queue.
+func (dlq *Queue) deadLetter(seq uint64) (DeadLetter, error) {
+	encoded, err := dlq.get(dlq.item(seq))
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	if encoded == nil {
+		return DeadLetter{}, fmt.Errorf("no dead letter %d: %w", seq, leveladt.ErrNotFound)
+	}
+	return decodeDeadLetter(seq, encoded)
+}
+
+// decodeDeadLetter deserializes the dead letter with sequence number seq
+func decodeDeadLetter(seq uint64, encoded []byte) (DeadLetter, error) {
+	qv, err := decode(encoded)
+	if err != nil {
+		return DeadLetter{}, err
+	}
+	return DeadLetter{
+		Seq:       seq,
+		Value:     []byte(qv.val),
+		Attempts:  int(qv.attempts),
+		LastError: qv.lastErr,
+	}, nil
+}
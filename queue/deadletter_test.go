@@ -0,0 +1,151 @@
+package queue
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestDeadLetters(t *testing.T) {
+	open := func(t *testing.T) (*Queue, *testClock) {
+		dir, err := ioutil.TempDir("", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := leveldb.OpenFile(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q := NewQueueWithOptions([]byte("test"), db, &Options{MaxDeliveries: 2})
+		clock := newTestClock()
+		q.now = clock.Now
+		return q, clock
+	}
+
+	t.Run("nack moves exhausted message", func(t *testing.T) {
+		assert := assert.New(t)
+		q, _ := open(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal(1, msg.Attempts)
+		assert.Nil(q.NackWithError(msg.ID, errors.New("first")))
+
+		msg, err = q.Receive()
+		assert.Nil(err)
+		assert.Equal(2, msg.Attempts)
+		assert.Nil(q.NackWithError(msg.ID, errors.New("second")))
+
+		_, err = q.Receive()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		dls, err := q.ListDeadLetters(0)
+		assert.Nil(err)
+		assert.Equal([]DeadLetter{{Seq: 0, Value: []byte("foo"), Attempts: 2, LastError: "second"}}, dls)
+
+		dl, err := q.InspectDeadLetter(0)
+		assert.Nil(err)
+		assert.Equal(dls[0], dl)
+
+		_, err = q.InspectDeadLetter(1)
+		assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+		// the dead-letter queue is a queue in its own right
+		got, err := q.DeadLetters().Peek()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("expired lease moves exhausted message", func(t *testing.T) {
+		assert := assert.New(t)
+		q, clock := open(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		_, err := q.Receive()
+		assert.Nil(err)
+		clock.Advance(DefaultVisibilityTimeout)
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+		assert.Equal(2, msg.Attempts)
+		clock.Advance(DefaultVisibilityTimeout)
+
+		// the expired lease of foo is dead-lettered and bar is delivered in its place
+		msg, err = q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), msg.Value)
+
+		dls, err := q.ListDeadLetters(0)
+		assert.Nil(err)
+		assert.Equal([]DeadLetter{{Seq: 0, Value: []byte("foo"), Attempts: 2, LastError: errVisibilityTimeout}}, dls)
+	})
+
+	t.Run("redrive and purge", func(t *testing.T) {
+		assert := assert.New(t)
+		q, _ := open(t)
+
+		for _, x := range []string{"foo", "bar", "baz"} {
+			assert.Nil(q.Enqueue([]byte(x)))
+			for i := 0; i < 2; i++ {
+				msg, err := q.Receive()
+				assert.Nil(err)
+				assert.Nil(q.Nack(msg.ID))
+			}
+		}
+
+		dls, err := q.ListDeadLetters(2)
+		assert.Nil(err)
+		assert.Len(dls, 2)
+		assert.Equal(errNack, dls[0].LastError)
+
+		moved, err := q.Redrive(1)
+		assert.Nil(err)
+		assert.Equal(1, moved)
+
+		// redriven messages start counting deliveries from scratch
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+		assert.Equal(1, msg.Attempts)
+
+		purged, err := q.PurgeDeadLetters()
+		assert.Nil(err)
+		assert.Equal(2, purged)
+
+		dls, err = q.ListDeadLetters(0)
+		assert.Nil(err)
+		assert.Empty(dls)
+
+		n, err := q.DeadLetters().Len()
+		assert.Nil(err)
+		assert.Equal(int64(0), n)
+	})
+
+	t.Run("unlimited deliveries by default", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		for i := 1; i <= 5; i++ {
+			msg, err := q.Receive()
+			assert.Nil(err)
+			assert.Equal(i, msg.Attempts)
+			assert.Nil(q.Nack(msg.ID))
+		}
+
+		dls, err := q.ListDeadLetters(0)
+		assert.Nil(err)
+		assert.Empty(dls)
+	})
+}
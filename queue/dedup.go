@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// prefix of the keys that index the IDs of recently enqueued items
+const pDedup = "dedup" // followed by a length-prefixed ID, holds the big-endian unix time in nanoseconds at which it may be reused
+
+// DefaultDedupWindow is the DedupWindow of a Queue created without one
+const DefaultDedupWindow = 5 * time.Minute
+
+// EnqueueWithID enqueues v to the back of the queue like Enqueue, unless an item was
+// enqueued with the same id within the dedup window of the queue, in which case it does
+// nothing. Retried producers can therefore enqueue the same item more than once without
+// creating duplicates.
+func (ls *Queue) EnqueueWithID(id string, v []byte, opts ...EnqueueOption) error {
+	return ls.admit(context.Background(), 1, uint64(len(v)), func() error {
+		now := ls.now()
+
+		seen, err := ls.seen(id, now)
+		if err != nil || seen {
+			return err
+		}
+
+		// the ID is recorded in the same batch as the item, so a crash cannot separate them
+		batch := new(leveldb.Batch)
+		batch.Put(ls.dedup(id), appendSeq(nil, uint64(now.Add(ls.o.DedupWindow).UnixNano())))
+		return ls.enqueueAt(batch, now, v, opts)
+	})
+}
+
+// seen returns true if an item was enqueued with id within the dedup window. Expired
+// index entries are left in place, to be overwritten or reaped.
+func (ls *Queue) seen(id string, now time.Time) (bool, error) {
+	until, err := ls.getSeq(ls.dedup(id))
+	if err != nil {
+		return false, err
+	}
+	return int64(until) > now.UnixNano(), nil
+}
+
+// reapDedup removes every expired entry of the dedup index, in batches of at most
+// purgeChunk entries, and returns the number of entries removed
+func (ls *Queue) reapDedup() (int, error) {
+	var (
+		removed int
+		cursor  = ls.special(pDedup)
+	)
+	for {
+		n, next, err := ls.reapDedupChunk(cursor)
+		removed += n
+		if err != nil || next == nil {
+			return removed, err
+		}
+		cursor = next
+	}
+}
+
+// reapDedupChunk removes the expired entries among the next purgeChunk entries of the
+// dedup index from key cursor on. It returns the number of entries removed, and the key
+// to continue from, which is nil once the end of the index was reached.
+func (ls *Queue) reapDedupChunk(cursor []byte) (int, []byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	prefix := ls.special(pDedup)
+	iter := ls.ldb.NewIterator(&util.Range{Start: cursor, Limit: util.BytesPrefix(prefix).Limit}, nil)
+	defer iter.Release()
+
+	now := uint64(ls.now().UnixNano())
+	batch := new(leveldb.Batch)
+	visited := 0
+	var next []byte
+	for iter.Next() {
+		if visited == purgeChunk {
+			next = append([]byte{}, iter.Key()...)
+			break
+		}
+		visited++
+		if _, ok := parseID(iter.Key(), len(prefix)); !ok {
+			continue
+		}
+
+		until, err := decodeSeq(iter.Value())
+		if err != nil {
+			return 0, nil, err
+		}
+		if until <= now {
+			batch.Delete(append([]byte{}, iter.Key()...))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+
+	if batch.Len() == 0 {
+		return 0, next, nil
+	}
+	return batch.Len(), next, ls.write(batch)
+}
+
+// dedup encodes the key that indexes the item enqueued with id
+func (ls *Queue) dedup(id string) []byte {
+	return appendID(ls.special(pDedup), id)
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func TestEnqueueWithID(t *testing.T) {
+	t.Run("duplicates are ignored", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+		assert.Nil(q.EnqueueWithID("b", []byte("bar")))
+
+		// consuming the item does not reopen the window
+		_, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+
+		got, err := q.DequeueBatch(10)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("bar")}, got)
+	})
+
+	t.Run("window passes", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		q.o.DedupWindow = time.Minute
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+		clock.Advance(time.Minute)
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), n)
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		clock := newTestClock()
+		q := NewQueue([]byte("test"), db)
+		q.now = clock.Now
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q = NewQueue([]byte("test"), db)
+		q.now = clock.Now
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+	})
+
+	t.Run("reaped once expired", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		q.o.DedupWindow = time.Minute
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueWithID("a", []byte("foo")))
+		clock.Advance(time.Second)
+		assert.Nil(q.EnqueueWithID("b", []byte("bar")))
+		clock.Advance(time.Minute - time.Second)
+
+		_, err := q.Reap()
+		assert.Nil(err)
+
+		iter := q.ldb.NewIterator(util.BytesPrefix(q.special(pDedup)), nil)
+		defer iter.Release()
+
+		var ids []string
+		for iter.Next() {
+			id, ok := parseID(iter.Key(), len(q.special(pDedup)))
+			assert.True(ok)
+			ids = append(ids, id)
+		}
+		assert.Equal([]string{"b"}, ids)
+	})
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// reason recorded for items moved to the dead-letter queue because they expired
+const errExpired = "time to live expired"
+
+// EnqueueOption configures a single enqueued item
+type EnqueueOption func(*enqueueOptions)
+
+type enqueueOptions struct {
+	ttl time.Duration
+}
+
+func makeEnqueueOptions(opts []EnqueueOption) enqueueOptions {
+	var o enqueueOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// value returns the queueValue of v, enqueued at time t
+func (o enqueueOptions) value(v []byte, t time.Time) queueValue {
+	qv := queueValue{val: string(v)}
+	if o.ttl > 0 {
+		qv.expires = t.Add(o.ttl).UnixNano()
+	}
+	return qv
+}
+
+// WithTTL sets the time to live of an item. Once d has elapsed after the item was
+// enqueued, it is no longer delivered, and is purged by the next dequeue that reaches it
+// or by Reap.
+func WithTTL(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.ttl = d
+	}
+}
+
+// Reap purges every expired item from the queue and returns the number of items purged.
+// Expired items are routed as configured by the queue options. Reap also removes the IDs
+// whose dedup window passed from the dedup index. Like RemoveIf, Reap works in batches of
+// bounded size.
+func (ls *Queue) Reap() (int, error) {
+	now := ls.now().UnixNano()
+	n, err := ls.removeWhere(func(qv queueValue) bool { return qv.expired(now) }, true)
+	if err != nil {
+		return n, err
+	}
+
+	_, err = ls.reapDedup()
+	return n, err
+}
+
+// RunReaper calls Reap every interval until ctx is done, and returns the error of ctx.
+// If Reap fails, RunReaper stops and returns the error.
+func (ls *Queue) RunReaper(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := ls.Reap(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package queue
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestTTL(t *testing.T) {
+	t.Run("expired items are skipped", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+		assert.Nil(q.Enqueue([]byte("bar")))
+		assert.Nil(q.Enqueue([]byte("baz"), WithTTL(time.Minute)))
+
+		clock.Advance(time.Minute)
+
+		got, err := q.Peek()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+
+		got, err = q.PeekBack()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+
+		got, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+
+		_, err = q.Dequeue()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(0), n)
+	})
+
+	t.Run("unexpired items are delivered", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+		clock.Advance(time.Second)
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+	})
+
+	t.Run("expired items are not redelivered", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+
+		_, err := q.Receive()
+		assert.Nil(err)
+
+		clock.Advance(time.Minute)
+
+		_, err = q.Receive()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+	})
+
+	t.Run("scheduled items live from when they are due", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(time.Hour, []byte("foo"), WithTTL(time.Minute)))
+		clock.Advance(time.Hour)
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("on expire", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		var expired []string
+		q.o.OnExpire = func(v []byte) { expired = append(expired, string(v)) }
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+		assert.Nil(q.Enqueue([]byte("bar"), WithTTL(time.Minute)))
+		assert.Nil(q.Enqueue([]byte("baz")))
+
+		clock.Advance(time.Minute)
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("baz"), got)
+		assert.Equal([]string{"foo", "bar"}, expired)
+	})
+
+	t.Run("expire to dead letter", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+		q.o.ExpireToDeadLetter = true
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+		clock.Advance(time.Minute)
+
+		_, err := q.Dequeue()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		dls, err := q.ListDeadLetters(10)
+		assert.Nil(err)
+		if assert.Len(dls, 1) {
+			assert.Equal([]byte("foo"), dls[0].Value)
+			assert.Equal(errExpired, dls[0].LastError)
+		}
+
+		// dead letters do not expire again once redriven
+		n, err := q.Redrive(1)
+		assert.Nil(err)
+		assert.Equal(1, n)
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+}
+
+func TestReap(t *testing.T) {
+	assert := assert.New(t)
+	q := openTestQueue(t)
+	clock := newTestClock()
+	q.now = clock.Now
+
+	var expired []string
+	q.o.OnExpire = func(v []byte) { expired = append(expired, string(v)) }
+
+	assert.Nil(q.Enqueue([]byte("foo")))
+	assert.Nil(q.Enqueue([]byte("bar"), WithTTL(time.Minute)))
+	assert.Nil(q.Enqueue([]byte("baz"), WithTTL(time.Hour)))
+	assert.Nil(q.Enqueue([]byte("qux"), WithTTL(time.Minute)))
+
+	clock.Advance(time.Minute)
+
+	n, err := q.Reap()
+	assert.Nil(err)
+	assert.Equal(2, n)
+	assert.Equal([]string{"bar", "qux"}, expired)
+
+	// reaping leaves gaps that are skipped over
+	length, err := q.Len()
+	assert.Nil(err)
+	assert.Equal(int64(2), length)
+
+	got, err := q.PeekBack()
+	assert.Nil(err)
+	assert.Equal([]byte("baz"), got)
+
+	vs, err := q.take(10)
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("foo"), []byte("baz")}, vs)
+
+	n, err = q.Reap()
+	assert.Nil(err)
+	assert.Equal(0, n)
+}
+
+func TestTTLRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	clock := newTestClock()
+	q := NewQueue([]byte("test"), db)
+	q.now = clock.Now
+
+	assert.Nil(q.Enqueue([]byte("foo")))
+	assert.Nil(q.Enqueue([]byte("bar"), WithTTL(time.Minute)))
+	assert.Nil(q.Enqueue([]byte("baz")))
+
+	clock.Advance(time.Minute)
+	n, err := q.Reap()
+	assert.Nil(err)
+	assert.Equal(1, n)
+
+	assert.Nil(db.Close())
+	db, err = leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	q = NewQueue([]byte("test"), db)
+	q.now = clock.Now
+
+	length, err := q.Len()
+	assert.Nil(err)
+	assert.Equal(int64(2), length)
+
+	for _, want := range []string{"foo", "baz"} {
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte(want), got)
+	}
+}
@@ -0,0 +1,164 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Item is an item of a queue, along with the sequence number it is stored under
+type Item struct {
+	Seq   uint64
+	Value []byte
+}
+
+// Iterator walks the items of a queue front to back, as they were when the iterator was
+// created, without dequeuing them. Scheduled items that are due follow the items of the
+// queue, numbered with the sequence numbers a dequeue would store them under. Expired
+// items are skipped. An Iterator must be released once it is no longer used.
+type Iterator struct {
+	q    *Queue
+	snap *leveldb.Snapshot
+	iter iterator.Iterator
+	due  iterator.Iterator // scheduled items that are due, nil if there are none
+	back uint64            // sequence number of the next due item
+	now  int64
+	item Item
+	err  error
+}
+
+// Iterator returns an iterator over a snapshot of the queue, so enqueues and dequeues
+// that happen while iterating are not seen
+func (ls *Queue) Iterator() (*Iterator, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	b, err := ls.bounds()
+	if err != nil {
+		return nil, err
+	}
+	earliest, err := ls.earliestDue()
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := ls.ldb.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("leveldb snapshot: %w", err)
+	}
+
+	it := &Iterator{
+		q:    ls,
+		snap: snap,
+		iter: snap.NewIterator(&util.Range{Start: ls.item(b.front), Limit: ls.item(b.back)}, nil),
+		back: b.back,
+		now:  ls.now().UnixNano(),
+	}
+	if earliest <= it.now {
+		prefix := ls.special(pSched)
+		it.due = snap.NewIterator(&util.Range{
+			Start: prefix,
+			Limit: appendSeq(prefix, uint64(it.now)+1),
+		}, nil)
+	}
+	return it, nil
+}
+
+// Next moves the iterator to the next item, and returns false once there are no more
+// items or an error occurred
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	for it.iter.Next() {
+		qv, err := decode(it.iter.Value())
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if qv.expired(it.now) {
+			continue
+		}
+
+		it.item = Item{Seq: it.q.seq(it.iter.Key()), Value: []byte(qv.val)}
+		return true
+	}
+	if err := it.iter.Error(); err != nil {
+		it.err = fmt.Errorf("leveldb iterate: %w", err)
+		return false
+	}
+
+	prefix := it.q.special(pSched)
+	for it.due != nil && it.due.Next() {
+		if !shaped(it.due.Key(), prefix, 16) {
+			continue
+		}
+		qv, err := decode(it.due.Value())
+		if err != nil {
+			it.err = err
+			return false
+		}
+		seq := it.back
+		it.back++
+		if qv.expired(it.now) {
+			continue
+		}
+
+		it.item = Item{Seq: seq, Value: []byte(qv.val)}
+		return true
+	}
+	if it.due != nil {
+		if err := it.due.Error(); err != nil {
+			it.err = fmt.Errorf("leveldb iterate: %w", err)
+		}
+	}
+	return false
+}
+
+// Item returns the current item
+func (it *Iterator) Item() Item {
+	return it.item
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Release releases the snapshot held by the iterator
+func (it *Iterator) Release() {
+	it.iter.Release()
+	if it.due != nil {
+		it.due.Release()
+	}
+	it.snap.Release()
+}
+
+// Range returns up to limit items, skipping the first offset items from the front of
+// the queue, without dequeuing them. The items are read from a single snapshot of the
+// queue. A limit of zero or less returns every item after offset.
+func (ls *Queue) Range(offset, limit int) ([]Item, error) {
+	it, err := ls.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	items := make([]Item, 0)
+	for i := 0; it.Next(); i++ {
+		if i < offset {
+			continue
+		}
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		items = append(items, it.Item())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator(t *testing.T) {
+	t.Run("front to back", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}))
+		_, err := q.Dequeue()
+		assert.Nil(err)
+
+		it, err := q.Iterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		var got []Item
+		for it.Next() {
+			got = append(got, it.Item())
+		}
+		assert.Nil(it.Err())
+		assert.Equal([]Item{{Seq: 1, Value: []byte("bar")}, {Seq: 2, Value: []byte("baz")}}, got)
+
+		// browsing does not consume
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), n)
+	})
+
+	t.Run("skips queues sharing the prefix", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(NewQueue(append(append([]byte{}, q.ns...), pItem...), q.ldb).Enqueue([]byte("bar")))
+
+		items, err := q.Range(0, 0)
+		assert.Nil(err)
+		assert.Equal([]Item{{Seq: 0, Value: []byte("foo")}}, items)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("foo"), []byte("bar")}))
+
+		it, err := q.Iterator()
+		assert.Nil(err)
+		defer it.Release()
+
+		_, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Nil(q.Enqueue([]byte("baz")))
+
+		var got []string
+		for it.Next() {
+			got = append(got, string(it.Item().Value))
+		}
+		assert.Nil(it.Err())
+		assert.Equal([]string{"foo", "bar"}, got)
+	})
+
+	t.Run("skips expired items", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Minute)))
+		assert.Nil(q.Enqueue([]byte("bar")))
+		clock.Advance(time.Minute)
+
+		items, err := q.Range(0, 0)
+		assert.Nil(err)
+		assert.Equal([]Item{{Seq: 1, Value: []byte("bar")}}, items)
+	})
+}
+
+func TestRange(t *testing.T) {
+	assert := assert.New(t)
+	q := openTestQueue(t)
+
+	assert.Nil(q.EnqueueBatch([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}))
+
+	items, err := q.Range(1, 2)
+	assert.Nil(err)
+	assert.Equal([]Item{{Seq: 1, Value: []byte("b")}, {Seq: 2, Value: []byte("c")}}, items)
+
+	items, err = q.Range(2, 0)
+	assert.Nil(err)
+	assert.Equal([]Item{{Seq: 2, Value: []byte("c")}, {Seq: 3, Value: []byte("d")}}, items)
+
+	items, err = q.Range(10, 1)
+	assert.Nil(err)
+	assert.Empty(items)
+}
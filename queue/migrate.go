@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// ErrLegacyLayout is returned by every operation on a queue that is still stored in the
+// linked list layout of earlier releases. Such a queue must be upgraded with Migrate.
+var ErrLegacyLayout = errors.New("queue: stored in the legacy linked list layout, upgrade it with Migrate")
+
+// Migrate rewrites the queue stored under namespace ns from the linked list layout of
+// earlier releases, where every gob-encoded item was the key of the item after it, to
+// sequence-numbered keys, in a single write. Queues already in the current layout are
+// left untouched, so Migrate is safe to call on every start.
+func Migrate(ns []byte, ldb *leveldb.DB) error {
+	ls := NewQueue(ns, ldb)
+
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	front, err := ls.get(ls.pFront())
+	if err != nil {
+		return err
+	}
+	back, err := ls.get(ls.pBack())
+	if err != nil {
+		return err
+	}
+	if !ls.legacy(front, back) {
+		return nil
+	}
+
+	batch := new(leveldb.Batch)
+	var b bounds
+
+	// an emptied linked list points its back at the key of the front pointer, and leaves
+	// the front pointer holding the last item removed
+	if !bytes.Equal(back, ls.pFront()) {
+		for key := front; key != nil; {
+			val, err := ls.decodeLinked(key)
+			if err != nil {
+				return err
+			}
+
+			encoded, err := encode(queueValue{val: val})
+			if err != nil {
+				return err
+			}
+			ls.push(batch, &b, encoded)
+
+			next, err := ls.get(key)
+			if err != nil {
+				return err
+			}
+			if next == nil && !bytes.Equal(key, back) {
+				return fmt.Errorf("linked queue ends before its back pointer: %w", leveladt.ErrCorrupt)
+			}
+			batch.Delete(key)
+			key = next
+		}
+	}
+
+	// the first enqueue to a fresh linked list also linked its item from the empty key
+	if first, err := ls.get(nil); err != nil {
+		return err
+	} else if first != nil {
+		if _, err := ls.decodeLinked(first); err == nil {
+			batch.Delete(nil)
+		}
+	}
+
+	ls.putBounds(batch, b)
+	return ls.write(batch)
+}
+
+// legacy returns true if the front and back pointers of the queue are those of the linked
+// list layout, which held encoded items instead of sequence numbers
+func (ls *Queue) legacy(front, back []byte) bool {
+	if back != nil && bytes.Equal(back, ls.pFront()) {
+		return true
+	}
+	return (front != nil && len(front) != 8) || (back != nil && len(back) != 8)
+}
+
+// decodeLinked returns the value of an item encoded by the linked list layout: the gob
+// encoding of the namespace, value and a 36 character nonce separated by spaces. The
+// value is found by position, so values holding spaces are recovered intact.
+func (ls *Queue) decodeLinked(encoded []byte) (string, error) {
+	var lv linkedValue
+	if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&lv); err != nil {
+		return "", fmt.Errorf("malformed linked queue item: %v: %w", err, leveladt.ErrCorrupt)
+	}
+
+	prefix := string(ls.ns) + " "
+	const suffix = 1 + 36 // space followed by the nonce
+	if len(lv) < len(prefix)+suffix || string(lv[:len(prefix)]) != prefix || lv[len(lv)-suffix] != ' ' {
+		return "", fmt.Errorf("linked queue item not in namespace %q: %w", ls.ns, leveladt.ErrCorrupt)
+	}
+	return string(lv[len(prefix) : len(lv)-suffix]), nil
+}
+
+// linkedValue captures the text an item of the linked list layout marshalled itself to
+type linkedValue []byte
+
+func (lv *linkedValue) UnmarshalBinary(data []byte) error {
+	*lv = append((*lv)[:0], data...)
+	return nil
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestMigrate(t *testing.T) {
+	openDB := func(t *testing.T) *leveldb.DB {
+		dir, err := ioutil.TempDir("", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, err := leveldb.OpenFile(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	t.Run("items", func(t *testing.T) {
+		assert := assert.New(t)
+		db := openDB(t)
+
+		lq := newLinkedQueue([]byte("test"), db)
+		for _, v := range []string{"foo", "bar baz", "qux\n"} {
+			assert.Nil(lq.Enqueue([]byte(v)))
+		}
+		_, err := lq.Dequeue()
+		assert.Nil(err)
+
+		q := NewQueue([]byte("test"), db)
+		_, err = q.Dequeue()
+		assert.True(errors.Is(err, ErrLegacyLayout))
+
+		assert.Nil(Migrate([]byte("test"), db))
+
+		// migrating twice leaves the current layout alone
+		assert.Nil(Migrate([]byte("test"), db))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), n)
+
+		assert.Nil(q.Enqueue([]byte("quux")))
+		for _, want := range []string{"bar baz", "qux\n", "quux"} {
+			got, err := q.Dequeue()
+			assert.Nil(err)
+			assert.Equal(want, string(got))
+		}
+
+		// no key of the linked list is left behind
+		iter := db.NewIterator(nil, nil)
+		defer iter.Release()
+		var keys []string
+		for iter.Next() {
+			keys = append(keys, string(iter.Key()))
+		}
+		assert.Nil(iter.Error())
+		assert.ElementsMatch([]string{"testfront", "testback", "testcount", "testbytes"}, keys)
+	})
+
+	t.Run("emptied queue", func(t *testing.T) {
+		assert := assert.New(t)
+		db := openDB(t)
+
+		// the namespace is chosen so that the key of the front pointer is 8 bytes long
+		lq := newLinkedQueue([]byte("xxx"), db)
+		assert.Nil(lq.Enqueue([]byte("foo")))
+		_, err := lq.Dequeue()
+		assert.Nil(err)
+
+		q := NewQueue([]byte("xxx"), db)
+		_, err = q.Len()
+		assert.True(errors.Is(err, ErrLegacyLayout))
+
+		assert.Nil(Migrate([]byte("xxx"), db))
+
+		empty, err := q.IsEmpty()
+		assert.Nil(err)
+		assert.True(empty)
+
+		assert.Nil(q.Enqueue([]byte("bar")))
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal("bar", string(got))
+	})
+
+	t.Run("current layout", func(t *testing.T) {
+		assert := assert.New(t)
+		db := openDB(t)
+
+		q := NewQueue([]byte("test"), db)
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(Migrate([]byte("test"), db))
+		assert.Nil(Migrate([]byte("other"), db))
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal("foo", string(got))
+	})
+}
@@ -1,10 +1,16 @@
 package queue
 
-import "errors"
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
 
 type queueModel struct {
 	ls         []string
 	lastPopped []byte
+	lastBatch  [][]byte // items removed by the last PopBatch
+	inflight   []string // received but unacknowledged items, in order of receipt
 }
 
 func makeQueueModel() queueModel {
@@ -18,7 +24,7 @@ func (mod *queueModel) Push(x []byte) error {
 
 func (mod *queueModel) Pop() ([]byte, error) {
 	if len(mod.ls) <= 0 {
-		return nil, errors.New("cannot pop from empty queue")
+		return nil, fmt.Errorf("cannot pop from empty queue: %w", leveladt.ErrEmpty)
 	}
 
 	front := mod.ls[0]
@@ -29,6 +35,70 @@ func (mod *queueModel) Pop() ([]byte, error) {
 	return []byte(front), nil
 }
 
+func (mod *queueModel) PushBatch(xs [][]byte) error {
+	for _, x := range xs {
+		mod.Push(x)
+	}
+	return nil
+}
+
+func (mod *queueModel) PopBatch(n int) ([][]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot pop from empty queue: %w", leveladt.ErrEmpty)
+	}
+	if n > len(mod.ls) {
+		n = len(mod.ls)
+	}
+
+	mod.lastBatch = make([][]byte, n)
+	for i, x := range mod.ls[:n] {
+		mod.lastBatch[i] = []byte(x)
+	}
+	mod.ls = mod.ls[n:]
+
+	return mod.lastBatch, nil
+}
+
+func (mod *queueModel) Receive() ([]byte, error) {
+	front, err := mod.Pop()
+	if err != nil {
+		return nil, err
+	}
+	mod.inflight = append(mod.inflight, string(front))
+	return front, nil
+}
+
+func (mod *queueModel) Ack(i int) error {
+	if i >= len(mod.inflight) {
+		return fmt.Errorf("no message in flight at %d: %w", i, leveladt.ErrNotFound)
+	}
+	mod.inflight = append(mod.inflight[:i], mod.inflight[i+1:]...)
+	return nil
+}
+
+func (mod *queueModel) Nack(i int) error {
+	if i >= len(mod.inflight) {
+		return fmt.Errorf("no message in flight at %d: %w", i, leveladt.ErrNotFound)
+	}
+	x := mod.inflight[i]
+	mod.inflight = append(mod.inflight[:i], mod.inflight[i+1:]...)
+	return mod.Push([]byte(x))
+}
+
+func (mod queueModel) Peek() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot peek into empty queue: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.ls[0]), nil
+}
+
+func (mod queueModel) PeekBack() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot peek into empty queue: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.ls[len(mod.ls)-1]), nil
+}
+
 func (mod queueModel) size() int {
 	return len(mod.ls)
 }
@@ -36,5 +106,7 @@ func (mod queueModel) size() int {
 func (mod queueModel) clone() queueModel {
 	cp := make([]string, len(mod.ls))
 	copy(cp, mod.ls)
-	return queueModel{ls: cp, lastPopped: mod.lastPopped}
+	inflight := make([]string, len(mod.inflight))
+	copy(inflight, mod.inflight)
+	return queueModel{ls: cp, lastPopped: mod.lastPopped, lastBatch: mod.lastBatch, inflight: inflight}
 }
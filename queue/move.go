@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Move pops the item at the front of src and pushes it to the back of dst in a single
+// write, so a crash can neither lose nor duplicate it, and returns the moved item. Both
+// queues must share the same LevelDB. The item keeps its time to live, but its delivery
+// attempts start over in dst. Move fails with an error wrapping leveladt.ErrFull if dst is
+// at capacity, whatever its overflow policy.
+func Move(src, dst *Queue) ([]byte, error) {
+	v, _, err := MoveIf(src, dst, func([]byte) bool { return true })
+	return v, err
+}
+
+// MoveIf moves the item at the front of src to the back of dst like Move, but only if
+// pred returns true for it. MoveIf returns the item at the front of src, and whether it
+// was moved.
+func MoveIf(src, dst *Queue, pred func(v []byte) bool) ([]byte, bool, error) {
+	if src.ldb != dst.ldb {
+		return nil, false, errors.New("cannot move between queues of different databases")
+	}
+	if src.dlq != nil && dst == src.dlq {
+		return nil, false, errors.New("cannot move to the dead-letter queue of the source queue")
+	}
+
+	unlock := lockPair(src, dst)
+	defer unlock()
+
+	if err := src.promote(); err != nil {
+		return nil, false, err
+	}
+
+	b, err := src.bounds()
+	if err != nil {
+		return nil, false, err
+	}
+	if b.empty() {
+		return nil, false, fmt.Errorf("cannot move from empty queue: %w", leveladt.ErrEmpty)
+	}
+
+	batch := new(leveldb.Batch)
+	qvs, expired, err := src.pop(batch, &b, 1)
+	if err != nil {
+		return nil, false, err
+	}
+	src.putBounds(batch, b)
+
+	if len(qvs) == 0 {
+		if err := src.commit(batch, expired); err != nil {
+			return nil, false, err
+		}
+		src.freed()
+		return nil, false, fmt.Errorf("cannot move from empty queue: %w", leveladt.ErrEmpty)
+	}
+
+	// leave src untouched, expired items included, if the item stays where it is
+	v := []byte(qvs[0].val)
+	if !pred(v) {
+		return v, false, nil
+	}
+
+	if src == dst {
+		encoded, err := encode(queueValue{val: qvs[0].val, expires: qvs[0].expires})
+		if err != nil {
+			return nil, false, err
+		}
+		src.push(batch, &b, encoded)
+		src.putBounds(batch, b)
+		if err := src.commit(batch, expired); err != nil {
+			return nil, false, err
+		}
+		return v, true, nil
+	}
+
+	db, err := dst.bounds()
+	if err != nil {
+		return nil, false, err
+	}
+	if !dst.fits(db, 1, uint64(len(v))) {
+		return nil, false, fmt.Errorf("cannot move item of %d bytes: %w", len(v), leveladt.ErrFull)
+	}
+
+	encoded, err := encode(queueValue{val: qvs[0].val, expires: qvs[0].expires})
+	if err != nil {
+		return nil, false, err
+	}
+	dst.push(batch, &db, encoded)
+	dst.putBounds(batch, db)
+
+	if err := src.commit(batch, expired); err != nil {
+		return nil, false, err
+	}
+
+	src.freed()
+	dst.notify(1)
+	return v, true, nil
+}
+
+// lockPair locks both queues in the order of their namespaces, so that concurrent moves
+// in opposite directions cannot deadlock, and returns a function that unlocks them
+func lockPair(a, b *Queue) func() {
+	if a == b {
+		a.l.Lock()
+		return a.l.Unlock
+	}
+	if bytes.Compare(a.ns, b.ns) > 0 {
+		a, b = b, a
+	}
+
+	a.l.Lock()
+	b.l.Lock()
+	return func() {
+		b.l.Unlock()
+		a.l.Unlock()
+	}
+}
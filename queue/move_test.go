@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestMove(t *testing.T) {
+	t.Run("move front to back", func(t *testing.T) {
+		assert := assert.New(t)
+		src := openTestQueue(t)
+		dst := NewQueue([]byte("processing"), src.ldb)
+
+		assert.Nil(src.Enqueue([]byte("foo")))
+		assert.Nil(src.Enqueue([]byte("bar")))
+		assert.Nil(dst.Enqueue([]byte("baz")))
+
+		got, err := Move(src, dst)
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+
+		n, err := src.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+
+		back, err := dst.PeekBack()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), back)
+	})
+
+	t.Run("move if", func(t *testing.T) {
+		assert := assert.New(t)
+		src := openTestQueue(t)
+		dst := NewQueue([]byte("processing"), src.ldb)
+
+		assert.Nil(src.Enqueue([]byte("foo")))
+
+		isBar := func(v []byte) bool { return bytes.Equal(v, []byte("bar")) }
+
+		got, moved, err := MoveIf(src, dst, isBar)
+		assert.Nil(err)
+		assert.False(moved)
+		assert.Equal([]byte("foo"), got)
+
+		n, err := src.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+
+		_, err = dst.Peek()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		assert.Nil(src.Enqueue([]byte("bar")))
+		_, err = src.Dequeue()
+		assert.Nil(err)
+
+		got, moved, err = MoveIf(src, dst, isBar)
+		assert.Nil(err)
+		assert.True(moved)
+		assert.Equal([]byte("bar"), got)
+	})
+
+	t.Run("empty source", func(t *testing.T) {
+		assert := assert.New(t)
+		src := openTestQueue(t)
+		dst := NewQueue([]byte("processing"), src.ldb)
+
+		_, err := Move(src, dst)
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+	})
+
+	t.Run("full destination", func(t *testing.T) {
+		assert := assert.New(t)
+		src := openTestQueue(t)
+		dst := NewQueueWithOptions([]byte("processing"), src.ldb, &Options{MaxLen: 1})
+
+		assert.Nil(src.Enqueue([]byte("foo")))
+		assert.Nil(dst.Enqueue([]byte("bar")))
+
+		_, err := Move(src, dst)
+		assert.True(errors.Is(err, leveladt.ErrFull))
+
+		// a failed move leaves the source untouched
+		got, err := src.Peek()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		_, err := Move(q, q)
+		assert.Nil(err)
+
+		got, err := q.DequeueBatch(2)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("bar"), []byte("foo")}, got)
+	})
+
+	t.Run("different databases", func(t *testing.T) {
+		assert := assert.New(t)
+		src := openTestQueue(t)
+		dst := openTestQueue(t)
+
+		assert.Nil(src.Enqueue([]byte("foo")))
+
+		_, err := Move(src, dst)
+		assert.NotNil(err)
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		src := NewQueue([]byte("test"), db)
+		dst := NewQueue([]byte("processing"), db)
+		assert.Nil(src.Enqueue([]byte("foo")))
+
+		_, err = Move(src, dst)
+		assert.Nil(err)
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		src = NewQueue([]byte("test"), db)
+		dst = NewQueue([]byte("processing"), db)
+
+		n, err := src.Len()
+		assert.Nil(err)
+		assert.Equal(int64(0), n)
+
+		got, err := dst.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("opposite directions", func(t *testing.T) {
+		assert := assert.New(t)
+		a := openTestQueue(t)
+		b := NewQueue([]byte("processing"), a.ldb)
+
+		assert.Nil(a.EnqueueBatch([][]byte{[]byte("foo"), []byte("bar")}))
+		assert.Nil(b.EnqueueBatch([][]byte{[]byte("baz"), []byte("qux")}))
+
+		var wg sync.WaitGroup
+		for _, pair := range [][2]*Queue{{a, b}, {b, a}} {
+			wg.Add(1)
+			go func(src, dst *Queue) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					if _, err := Move(src, dst); err != nil && !errors.Is(err, leveladt.ErrEmpty) {
+						assert.Nil(err)
+					}
+				}
+			}(pair[0], pair[1])
+		}
+		wg.Wait()
+
+		na, err := a.Len()
+		assert.Nil(err)
+		nb, err := b.Len()
+		assert.Nil(err)
+		assert.Equal(int64(4), na+nb)
+	})
+}
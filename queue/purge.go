@@ -0,0 +1,281 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// purgeChunk is the maximum number of keys removed by a single batch of Purge, RemoveIf
+// and Reap. The queue lock is released between batches, so other operations make
+// progress while a large queue is purged.
+const purgeChunk = 1000
+
+// Purge removes every message of the queue, whether ready, in flight, scheduled or dead,
+// along with the keys that track the state of the queue, and returns the number of
+// messages removed. Purge works in batches of bounded size, so messages added while it
+// runs may or may not be removed.
+func (ls *Queue) Purge() (int, error) {
+	removed, err := ls.purge()
+	if err != nil {
+		return removed, err
+	}
+
+	n, err := ls.PurgeDeadLetters()
+	return removed + n, err
+}
+
+// purge removes every message of the queue but its dead letters, and the keys that
+// track its state, and returns the number of messages removed
+func (ls *Queue) purge() (int, error) {
+	removed, err := ls.removeWhere(func(queueValue) bool { return true }, false)
+	if err != nil {
+		return removed, err
+	}
+
+	n, err := ls.purgeInflight()
+	removed += n
+	if err != nil {
+		return removed, err
+	}
+
+	n, err = ls.purgeKeys(pSched, ls.isScheduled)
+	removed += n
+	if err != nil {
+		return removed, err
+	}
+
+	if _, err := ls.purgeKeys(pDedup, ls.isDedup); err != nil {
+		return removed, err
+	}
+
+	return removed, ls.purgeState()
+}
+
+// RemoveIf removes every item of the queue for which pred returns true, and returns the
+// number of items removed. Items in flight or scheduled for later are not visited.
+// RemoveIf works in batches of bounded size, so it does not see a consistent snapshot of
+// a queue that changes while it runs.
+func (ls *Queue) RemoveIf(pred func(v []byte) bool) (int, error) {
+	return ls.removeWhere(func(qv queueValue) bool { return pred([]byte(qv.val)) }, false)
+}
+
+// removeWhere removes the items of the queue that match, front to back, in batches of
+// at most purgeChunk items, and returns the number of items removed. If route is true,
+// the removed items are routed like expired items.
+func (ls *Queue) removeWhere(match func(qv queueValue) bool, route bool) (int, error) {
+	var (
+		removed int
+		cursor  uint64
+	)
+	for {
+		n, next, done, err := ls.removeChunk(cursor, match, route)
+		removed += n
+		if err != nil || done {
+			return removed, err
+		}
+		cursor = next
+	}
+}
+
+// removeChunk removes the items that match among the next purgeChunk items from the
+// sequence number cursor on, in one batch. It returns the number of items removed, the
+// cursor to continue from, and whether the end of the queue was reached.
+func (ls *Queue) removeChunk(cursor uint64, match func(qv queueValue) bool, route bool) (int, uint64, bool, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	b, err := ls.bounds()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if cursor < b.front {
+		cursor = b.front
+	}
+
+	iter := ls.ldb.NewIterator(&util.Range{Start: ls.item(cursor), Limit: ls.item(b.back)}, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	var removed []queueValue
+	visited := 0
+	for visited < purgeChunk && iter.Next() {
+		visited++
+		cursor = ls.seq(iter.Key()) + 1
+
+		decoded, err := decode(iter.Value())
+		if err != nil {
+			return 0, 0, false, err
+		}
+		if !match(decoded) {
+			continue
+		}
+
+		batch.Delete(append([]byte{}, iter.Key()...))
+		b.count--
+		b.bytes -= uint64(len(decoded.val))
+		removed = append(removed, decoded)
+	}
+	if err := iter.Error(); err != nil {
+		return 0, 0, false, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	done := visited < purgeChunk
+
+	if len(removed) == 0 {
+		return 0, cursor, done, nil
+	}
+
+	if b.empty() {
+		b.front = b.back
+	}
+	ls.putBounds(batch, b)
+
+	if route {
+		err = ls.commit(batch, removed)
+	} else {
+		err = ls.write(batch)
+	}
+	if err != nil {
+		return 0, 0, false, err
+	}
+	ls.freed()
+	return len(removed), cursor, done, nil
+}
+
+// purgeInflight removes every message in flight, along with its lease, in batches of at
+// most purgeChunk messages, and returns the number of messages removed
+func (ls *Queue) purgeInflight() (int, error) {
+	removed := 0
+	for {
+		n, err := ls.purgeInflightChunk()
+		removed += n
+		if err != nil || n < purgeChunk {
+			return removed, err
+		}
+	}
+}
+
+func (ls *Queue) purgeInflightChunk() (int, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	prefix := ls.special(pInflight)
+	iter := ls.ldb.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	n := 0
+	for n < purgeChunk && iter.Next() {
+		id, ok := parseID(iter.Key(), len(prefix))
+		if !ok {
+			continue
+		}
+		l, err := decodeLease(iter.Value())
+		if err != nil {
+			return 0, err
+		}
+		ls.release(batch, id, l)
+		n++
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, ls.write(batch)
+}
+
+// purgeKeys removes every key under the special prefix name that owned accepts, in
+// batches of at most purgeChunk keys, and returns the number of keys removed
+func (ls *Queue) purgeKeys(name string, owned func(key []byte) bool) (int, error) {
+	removed := 0
+	for {
+		n, err := ls.purgeKeysChunk(name, owned)
+		removed += n
+		if err != nil || n < purgeChunk {
+			return removed, err
+		}
+	}
+}
+
+func (ls *Queue) purgeKeysChunk(name string, owned func(key []byte) bool) (int, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	iter := ls.ldb.NewIterator(util.BytesPrefix(ls.special(name)), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for batch.Len() < purgeChunk && iter.Next() {
+		if owned(iter.Key()) {
+			batch.Delete(append([]byte{}, iter.Key()...))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return 0, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	return batch.Len(), ls.write(batch)
+}
+
+// purgeState removes the keys that track the state of the queue, unless messages were
+// added since they were purged. The sequence numbers of a queue start over once its
+// state is removed, so they are only removed while no key could collide with them.
+func (ls *Queue) purgeState() error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	b, err := ls.bounds()
+	if err != nil {
+		return err
+	}
+	scheduled, err := ls.hasKeys(pSched, ls.isScheduled)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	if b.empty() {
+		batch.Delete(ls.pFront())
+		batch.Delete(ls.pBack())
+		batch.Delete(ls.special(pCount))
+		batch.Delete(ls.special(pBytes))
+	}
+	if !scheduled {
+		batch.Delete(ls.special(pSchedSeq))
+	}
+	ls.dueKnown = false
+	return ls.write(batch)
+}
+
+// hasKeys returns true if any key under the special prefix name is accepted by owned
+func (ls *Queue) hasKeys(name string, owned func(key []byte) bool) (bool, error) {
+	iter := ls.ldb.NewIterator(util.BytesPrefix(ls.special(name)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if owned(iter.Key()) {
+			return true, nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return false, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return false, nil
+}
+
+// isScheduled returns true if key, found under the schedule prefix, is the key of a
+// scheduled item of this queue
+func (ls *Queue) isScheduled(key []byte) bool {
+	return shaped(key, ls.special(pSched), 16)
+}
+
+// isDedup returns true if key, found under the dedup prefix, indexes an ID of this queue
+func (ls *Queue) isDedup(key []byte) bool {
+	_, ok := parseID(key, len(ls.special(pDedup)))
+	return ok
+}
@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func TestPurge(t *testing.T) {
+	assert := assert.New(t)
+	q := openTestQueue(t)
+	q.o.MaxDeliveries = 1
+	clock := newTestClock()
+	q.now = clock.Now
+
+	// a message of every kind: ready, in flight, scheduled and dead
+	assert.Nil(q.EnqueueBatch([][]byte{[]byte("dead"), []byte("inflight"), []byte("ready")}))
+	msg, err := q.Receive()
+	assert.Nil(err)
+	assert.Nil(q.Nack(msg.ID))
+	_, err = q.Receive()
+	assert.Nil(err)
+	assert.Nil(q.EnqueueAfter(time.Minute, []byte("scheduled")))
+
+	n, err := q.Purge()
+	assert.Nil(err)
+	assert.Equal(4, n)
+
+	// nothing is left under the namespace of the queue
+	iter := q.ldb.NewIterator(util.BytesPrefix(q.ns), nil)
+	defer iter.Release()
+	assert.False(iter.First())
+
+	// the queue starts over
+	clock.Advance(time.Minute)
+	_, err = q.Dequeue()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	assert.Nil(q.Enqueue([]byte("foo")))
+	items, err := q.Range(0, 0)
+	assert.Nil(err)
+	assert.Equal([]Item{{Seq: 0, Value: []byte("foo")}}, items)
+}
+
+func TestPurgeChunks(t *testing.T) {
+	assert := assert.New(t)
+	q := openTestQueue(t)
+
+	vs := make([][]byte, 2*purgeChunk+1)
+	for i := range vs {
+		vs[i] = []byte(fmt.Sprint(i))
+	}
+	assert.Nil(q.EnqueueBatch(vs))
+
+	n, err := q.Purge()
+	assert.Nil(err)
+	assert.Equal(len(vs), n)
+
+	length, err := q.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), length)
+}
+
+func TestPurgeSharedPrefix(t *testing.T) {
+	assert := assert.New(t)
+	q := openTestQueue(t)
+	clock := newTestClock()
+
+	// a message of every kind in q, and in queues whose namespaces extend that of q with
+	// the prefixes of its keys, including the suffix its dead-letter queue used to have
+	fill := func(q *Queue) {
+		q.o.MaxDeliveries = 1
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueWithID("a", []byte("dead")))
+		assert.Nil(q.EnqueueWithID("b", []byte("inflight")))
+		assert.Nil(q.Enqueue([]byte("ready")))
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Nil(q.Nack(msg.ID))
+		_, err = q.Receive()
+		assert.Nil(err)
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("scheduled")))
+	}
+
+	fill(q)
+	var others []*Queue
+	for _, name := range []string{pItem, pInflight, pLease, pSched, pDedup, "dead"} {
+		other := NewQueue(append(append([]byte{}, q.ns...), name...), q.ldb)
+		fill(other)
+		others = append(others, other)
+	}
+
+	dls, err := q.ListDeadLetters(0)
+	assert.Nil(err)
+	assert.Len(dls, 1)
+
+	n, err := q.Purge()
+	assert.Nil(err)
+	assert.Equal(4, n)
+
+	for _, other := range others {
+		// the IDs of the other queue are still known
+		assert.Nil(other.EnqueueWithID("a", []byte("duplicate")))
+
+		n, err := other.Purge()
+		assert.Nil(err, "queue %q", other.ns)
+		assert.Equal(4, n, "queue %q", other.ns)
+	}
+
+	iter := q.ldb.NewIterator(util.BytesPrefix(q.ns), nil)
+	defer iter.Release()
+	assert.False(iter.First())
+}
+
+func TestRemoveIf(t *testing.T) {
+	t.Run("removes matching items", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("a"), []byte("b"), []byte("a"), []byte("c")}))
+
+		n, err := q.RemoveIf(func(v []byte) bool { return bytes.Equal(v, []byte("a")) })
+		assert.Nil(err)
+		assert.Equal(2, n)
+
+		length, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), length)
+
+		got, err := q.DequeueBatch(10)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("b"), []byte("c")}, got)
+	})
+
+	t.Run("across chunks", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		vs := make([][]byte, 2*purgeChunk+1)
+		for i := range vs {
+			vs[i] = []byte{byte(i % 2)}
+		}
+		assert.Nil(q.EnqueueBatch(vs))
+
+		n, err := q.RemoveIf(func(v []byte) bool { return v[0] == 1 })
+		assert.Nil(err)
+		assert.Equal(purgeChunk, n)
+
+		length, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(purgeChunk+1), length)
+
+		b, err := q.bounds()
+		assert.Nil(err)
+		assert.Equal(uint64(purgeChunk+1), b.bytes)
+	})
+
+	t.Run("empties queue", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("a"), []byte("b")}))
+
+		n, err := q.RemoveIf(func([]byte) bool { return true })
+		assert.Nil(err)
+		assert.Equal(2, n)
+
+		assert.Nil(q.Enqueue([]byte("c")))
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("c"), got)
+	})
+}
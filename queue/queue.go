@@ -1,109 +1,376 @@
 package queue
 
 import (
-	"bytes"
-	"encoding/gob"
-	"errors"
+	"context"
+	"encoding/binary"
 	"fmt"
 	"sync"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/lyonssp/leveladt"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-// special key that always points to the pFront of the queue
+// special keys that hold the sequence numbers delimiting the queue, and the prefix of
+// the keys that hold its items
 const (
-	pFront = "front"
-	pBack  = "back"
+	pFront = "front" // sequence number of the item at the front of the queue
+	pBack  = "back"  // sequence number the next enqueued item will be stored under
+	pCount = "count" // number of items stored between front and back
+	pBytes = "bytes" // total size of the values stored between front and back
+	pItem  = "item"  // followed by the big-endian sequence number of an item
 )
 
-// Queue is a FIFO queue backed by LevelDB
+// Queue is a FIFO queue backed by LevelDB.
+//
+// Every item is stored under its own sequence number, and the front and back sequence
+// numbers are updated in the same batch as the items they delimit, so enqueue, dequeue
+// and length are all O(1) and the keyspace of a queue sorts in FIFO order. Items removed
+// from the middle of the queue, such as expired items, leave gaps in the sequence that
+// dequeues skip over.
 type Queue struct {
 	ns  []byte
 	ldb *leveldb.DB
 	l   sync.Mutex
+	o   Options
+
+	// consumers blocked on an empty queue, in arrival order
+	waiters []chan struct{}
+
+	// closed when an item is scheduled, to wake consumers waiting for the next due time
+	schedCh chan struct{}
+
+	// earliest due time of a scheduled item in unix nanoseconds, or math.MaxInt64 if no
+	// item is scheduled. Only valid while dueKnown is set, otherwise the schedule is read
+	// again on next use.
+	earliest int64
+	dueKnown bool
+
+	// closed when items are removed, to wake producers blocked on a full queue
+	roomCh chan struct{}
+
+	// source of the current time, replaced by tests
+	now func() time.Time
+
+	// writes a batch to LevelDB, replaced by tests to inject failures
+	writeBatch func(batch *leveldb.Batch) error
+
+	// dead-letter queue, created on first use
+	dlq *Queue
+}
+
+// Options configure the behaviour of a Queue. Zero values select the documented defaults.
+type Options struct {
+	// VisibilityTimeout is how long a message returned by Receive stays in flight before
+	// it is delivered again. Defaults to DefaultVisibilityTimeout.
+	VisibilityTimeout time.Duration
+
+	// MaxDeliveries is the number of times Receive delivers an item before a failed
+	// delivery moves it to the dead-letter queue. Zero means items are delivered until
+	// they are acknowledged.
+	MaxDeliveries int
+
+	// OnExpire, if set, is called with every item whose time to live passed, after the
+	// item is purged from the queue. It is called with the queue lock held and must not
+	// use the queue.
+	OnExpire func(v []byte)
+
+	// ExpireToDeadLetter moves items whose time to live passed to the dead-letter queue,
+	// in the same batch that purges them, instead of discarding them
+	ExpireToDeadLetter bool
+
+	// MaxLen is the maximum number of items in the queue, and MaxBytes the maximum total
+	// size of their values. Zero means unbounded. Both limit Len, so items returning from
+	// flight, from the schedule or from the dead-letter queue are admitted regardless.
+	MaxLen   int64
+	MaxBytes int64
+
+	// Overflow decides what an enqueue does when the queue is at capacity. Defaults to
+	// OverflowReject.
+	Overflow OverflowPolicy
+
+	// DedupWindow is how long EnqueueWithID ignores an ID after enqueuing an item with it.
+	// Defaults to DefaultDedupWindow.
+	DedupWindow time.Duration
 }
 
+// DefaultVisibilityTimeout is the VisibilityTimeout of a Queue created without one
+const DefaultVisibilityTimeout = 30 * time.Second
+
 func NewQueue(ns []byte, ldb *leveldb.DB) *Queue {
-	return &Queue{
+	return NewQueueWithOptions(ns, ldb, nil)
+}
+
+// NewQueueWithOptions returns the queue stored under namespace ns, configured by o.
+// A nil o is equivalent to the zero Options.
+func NewQueueWithOptions(ns []byte, ldb *leveldb.DB, o *Options) *Queue {
+	ls := &Queue{
 		ns:  ns,
 		ldb: ldb,
+		now: time.Now,
+	}
+	ls.writeBatch = func(batch *leveldb.Batch) error {
+		return ls.ldb.Write(batch, nil)
+	}
+	if o != nil {
+		ls.o = *o
+	}
+	if ls.o.VisibilityTimeout <= 0 {
+		ls.o.VisibilityTimeout = DefaultVisibilityTimeout
+	}
+	if ls.o.DedupWindow <= 0 {
+		ls.o.DedupWindow = DefaultDedupWindow
+	}
+	return ls
+}
+
+// Enqueue the value x to the back of the queue. If the queue is at capacity, Enqueue
+// behaves as configured by the overflow policy of the queue, blocking without a deadline
+// under OverflowBlock.
+func (ls *Queue) Enqueue(v []byte, opts ...EnqueueOption) error {
+	return ls.EnqueueWait(context.Background(), v, opts...)
+}
+
+// EnqueueBatch enqueues every value of vs to the back of the queue, in order, in a single
+// write. Either all of the values are enqueued or none are.
+// The whole batch must fit in the queue, as decided by the overflow policy of the queue.
+func (ls *Queue) EnqueueBatch(vs [][]byte, opts ...EnqueueOption) error {
+	if len(vs) == 0 {
+		return nil
 	}
+
+	var size uint64
+	for _, v := range vs {
+		size += uint64(len(v))
+	}
+
+	return ls.admit(context.Background(), uint64(len(vs)), size, func() error {
+		o := makeEnqueueOptions(opts)
+		now := ls.now()
+
+		b, err := ls.bounds()
+		if err != nil {
+			return err
+		}
+
+		batch := new(leveldb.Batch)
+		expired, err := ls.makeRoom(batch, &b, uint64(len(vs)), size)
+		if err != nil {
+			return err
+		}
+		for _, v := range vs {
+			encoded, err := encode(o.value(v, now))
+			if err != nil {
+				return err
+			}
+			ls.push(batch, &b, encoded)
+		}
+		ls.putBounds(batch, b)
+		if err := ls.commit(batch, expired); err != nil {
+			return err
+		}
+
+		ls.notify(len(vs))
+		return nil
+	})
 }
 
-// Enqueue the value x to the back of the queue
-func (ls *Queue) Enqueue(v []byte) error {
+// Dequeue and return the item at the front of the queue
+func (ls *Queue) Dequeue() ([]byte, error) {
 	ls.l.Lock()
 	defer ls.l.Unlock()
 
-	// encode input with namespace and deduplicating nonce
-	encoded, err := encode(queueValue{ns: string(ls.ns), val: string(v), nonce: uuid.NewString()})
+	vs, err := ls.take(1)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return vs[0], nil
+}
 
-	// get encoded queueValue at back of queue
-	encBack, err := ls.peekBack()
-	if err != nil {
-		return err
+// DequeueBatch removes and returns up to n items from the front of the queue in a single
+// write. Either all of the returned items are removed or none are.
+func (ls *Queue) DequeueBatch(n int) ([][]byte, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", n)
 	}
 
-	batch := new(leveldb.Batch)
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	return ls.take(n)
+}
+
+// Peek returns the item at the front of the queue without removing it
+func (ls *Queue) Peek() ([]byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
 
-	// if the back pointer points to nothing, this is the first write to the queue and the front pointer must be updated
-	if encBack == nil {
-		batch.Put(ls.pFront(), encoded)
+	return ls.peekValue(ls.peek)
+}
+
+// PeekBack returns the item at the back of the queue without removing it
+func (ls *Queue) PeekBack() ([]byte, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	return ls.peekValue(ls.peekBack)
+}
+
+// Len returns the number of items in the queue, read from its bounds in O(1). Scheduled
+// items count once they are moved into the queue, which every call that writes to the
+// queue does once they are due, so Len leaves out due items that Peek already sees until
+// the next such call. Expired items count until they are purged by a dequeue or Reap, so
+// Len can be positive while Dequeue finds the queue empty.
+func (ls *Queue) Len() (int64, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	b, err := ls.bounds()
+	if err != nil {
+		return 0, err
 	}
-	batch.Put(encBack, encoded)
-	batch.Put(ls.pBack(), encoded)
-	return ls.ldb.Write(batch, nil)
+	return int64(b.size()), nil
 }
 
-// Dequeue and return the item at the front of the queue
-func (ls *Queue) Dequeue() ([]byte, error) {
+// IsEmpty returns true if the queue holds no items that a dequeue would return, and false
+// otherwise. Unlike Len, IsEmpty skips expired items, visiting every expired item at the
+// front of the queue.
+func (ls *Queue) IsEmpty() (bool, error) {
 	ls.l.Lock()
 	defer ls.l.Unlock()
 
-	// get encoded queueValue at front of the queue that will be removed
-	encFront, err := ls.peek()
+	encoded, err := ls.peek()
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	if encFront == nil {
-		return nil, errors.New("cannot pop from empty queue")
+	return encoded == nil, nil
+}
+
+// take removes and returns up to max items from the front of the queue in a single batch.
+// The caller must hold the queue lock.
+func (ls *Queue) take(max int) ([][]byte, error) {
+	if err := ls.promote(); err != nil {
+		return nil, err
 	}
 
-	// get the item that will be the new front of the queue
-	newEncFront, err := ls.get(encFront)
+	b, err := ls.bounds()
 	if err != nil {
 		return nil, err
 	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot pop from empty queue: %w", leveladt.ErrEmpty)
+	}
 
-	// start batch updates
+	// remove the front items, and any expired items before them, and advance the front
+	// pointer past them in one batch
 	batch := new(leveldb.Batch)
+	qvs, expired, err := ls.pop(batch, &b, max)
+	if err != nil {
+		return nil, err
+	}
+	ls.putBounds(batch, b)
 
-	// include delete for the item at the front of the queue
-	batch.Delete(encFront)
+	if err := ls.commit(batch, expired); err != nil {
+		return nil, err
+	}
+	ls.freed()
+	if len(qvs) == 0 {
+		return nil, fmt.Errorf("cannot pop from empty queue: %w", leveladt.ErrEmpty)
+	}
 
-	// if there was a second item in the queue, update the front pointer
-	// otherwise, we need to update the back pointer to point to front
-	if newEncFront != nil {
-		batch.Put(ls.pFront(), newEncFront)
-	} else {
-		batch.Put(ls.pBack(), ls.pFront())
+	vs := make([][]byte, len(qvs))
+	for i, qv := range qvs {
+		vs[i] = []byte(qv.val)
 	}
+	return vs, nil
+}
 
-	if err := ls.ldb.Write(batch, nil); err != nil {
-		return nil, err
+// pop includes the removal of up to max items from the front of the queue in batch,
+// advancing b past them, and returns the removed items. Expired items found on the way
+// are removed too, and returned separately. The caller must write b back in the same
+// batch, and route the expired items with commit.
+func (ls *Queue) pop(batch *leveldb.Batch, b *bounds, max int) ([]queueValue, []queueValue, error) {
+	now := ls.now().UnixNano()
+
+	iter := ls.ldb.NewIterator(&util.Range{Start: ls.item(b.front), Limit: ls.item(b.back)}, nil)
+	defer iter.Release()
+
+	var qvs, expired []queueValue
+	for len(qvs) < max && iter.Next() {
+		// decode and parse originally pushed value
+		decoded, err := decode(iter.Value())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		batch.Delete(append([]byte{}, iter.Key()...))
+		b.front = ls.seq(iter.Key()) + 1
+		b.count--
+		b.bytes -= uint64(len(decoded.val))
+
+		if decoded.expired(now) {
+			expired = append(expired, decoded)
+			continue
+		}
+		qvs = append(qvs, decoded)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+
+	if b.empty() {
+		b.front = b.back
+	}
+	return qvs, expired, nil
+}
+
+// push includes the write of an encoded queueValue to the back of the queue in batch,
+// advancing b past it. The caller must write b back in the same batch.
+func (ls *Queue) push(batch *leveldb.Batch, b *bounds, encoded []byte) {
+	batch.Put(ls.item(b.back), encoded)
+	b.back++
+	b.count++
+	b.bytes += valueSize(encoded)
+}
+
+// commit writes batch, which removed the expired items, routing the expired items as
+// configured by the queue options
+func (ls *Queue) commit(batch *leveldb.Batch, expired []queueValue) error {
+	if len(expired) > 0 && ls.o.ExpireToDeadLetter {
+		for i := range expired {
+			expired[i].lastErr = errExpired
+		}
+		if err := ls.kill(batch, expired...); err != nil {
+			return err
+		}
+	} else if err := ls.write(batch); err != nil {
+		return err
 	}
 
-	// decode and parse originally pushed value
-	frontDecoded, err := decode(encFront)
+	if ls.o.OnExpire != nil {
+		for _, qv := range expired {
+			ls.o.OnExpire([]byte(qv.val))
+		}
+	}
+	return nil
+}
+
+// peekValue decodes the item returned by one of the peek helpers
+func (ls *Queue) peekValue(peek func() ([]byte, error)) ([]byte, error) {
+	encoded, err := peek()
 	if err != nil {
 		return nil, err
 	}
+	if encoded == nil {
+		return nil, fmt.Errorf("cannot peek into empty queue: %w", leveladt.ErrEmpty)
+	}
 
-	return []byte(frontDecoded.val), nil
+	decoded, err := decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(decoded.val), nil
 }
 
 /*
@@ -117,72 +384,306 @@ func (ls *Queue) get(key []byte) ([]byte, error) {
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("leveldb get: %w", err)
 	}
 
 	return front, nil
 }
 
+func (ls *Queue) write(batch *leveldb.Batch) error {
+	if err := ls.writeBatch(batch); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
 // pFront encodes the pFront constant, respecting the namespace of the queue
 func (ls *Queue) pFront() []byte {
-	var b bytes.Buffer
-	fmt.Fprint(&b, string(ls.ns), pFront)
-	return b.Bytes()
+	return ls.special(pFront)
 }
 
 // pBack encodes the pBack constant, respecting the namespace of the queue
 func (ls *Queue) pBack() []byte {
-	var b bytes.Buffer
-	fmt.Fprint(&b, string(ls.ns), pBack)
-	return b.Bytes()
+	return ls.special(pBack)
+}
+
+// pItem encodes the pItem prefix, respecting the namespace of the queue
+func (ls *Queue) pItem() []byte {
+	return ls.special(pItem)
+}
+
+// special encodes a special key or prefix, respecting the namespace of the queue
+func (ls *Queue) special(name string) []byte {
+	namespaced := make([]byte, len(ls.ns)+len(name))
+	copy(namespaced, ls.ns)
+	copy(namespaced[len(ls.ns):], name)
+	return namespaced
 }
 
-// peek returns the encoded queueValue at the front of the queue
+// item encodes the key of the item with sequence number seq
+func (ls *Queue) item(seq uint64) []byte {
+	return appendSeq(ls.pItem(), seq)
+}
+
+// seq decodes the sequence number of an item from its key
+func (ls *Queue) seq(key []byte) uint64 {
+	return binary.BigEndian.Uint64(key[len(key)-8:])
+}
+
+// shaped returns true if key, found under prefix, is prefix followed by exactly n bytes.
+// Namespaces are not delimited, so a scan of a prefix also finds the keys of queues whose
+// namespace extends this one, which do not have the shape of the keys stored there.
+func shaped(key, prefix []byte, n int) bool {
+	return len(key) == len(prefix)+n
+}
+
+// peek returns the encoded queueValue at the front of the queue, skipping expired items.
+// Scheduled items that are due follow the items of the queue.
 func (ls *Queue) peek() ([]byte, error) {
-	return ls.get(ls.pFront())
+	encoded, err := ls.peekFrom(false)
+	if err != nil || encoded != nil {
+		return encoded, err
+	}
+	return ls.peekDue(false)
 }
 
-// peekBack returns the encoded queueValue at the back of the queue
+// peekBack returns the encoded queueValue at the back of the queue, skipping expired items
 func (ls *Queue) peekBack() ([]byte, error) {
-	return ls.get(ls.pBack())
+	encoded, err := ls.peekDue(true)
+	if err != nil || encoded != nil {
+		return encoded, err
+	}
+	return ls.peekFrom(true)
+}
+
+// peekFrom returns the first encoded queueValue that has not expired, visiting items
+// from the front of the queue, or from the back if backward is true
+func (ls *Queue) peekFrom(backward bool) ([]byte, error) {
+	b, err := ls.bounds()
+	if err != nil || b.empty() {
+		return nil, err
+	}
+
+	iter := ls.ldb.NewIterator(&util.Range{Start: ls.item(b.front), Limit: ls.item(b.back)}, nil)
+	defer iter.Release()
+
+	first, step := iter.First, iter.Next
+	if backward {
+		first, step = iter.Last, iter.Prev
+	}
+
+	now := ls.now().UnixNano()
+	for ok := first(); ok; ok = step() {
+		decoded, err := decode(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if !decoded.expired(now) {
+			return append([]byte{}, iter.Value()...), nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return nil, nil
+}
+
+// bounds holds the sequence numbers delimiting the items of a queue, and the number and
+// total value size of the items between them. Items are stored under sequence numbers from front up to, but
+// excluding, back, except where items were removed from the middle of the queue.
+type bounds struct {
+	front uint64
+	back  uint64
+	count uint64
+	bytes uint64
+}
+
+func (b bounds) empty() bool {
+	return b.count == 0
+}
+
+func (b bounds) size() uint64 {
+	return b.count
+}
+
+// bounds reads the durable front and back sequence numbers, item count and value size of
+// the queue. A queue that was never written to has all of them at zero.
+func (ls *Queue) bounds() (bounds, error) {
+	front, err := ls.get(ls.pFront())
+	if err != nil {
+		return bounds{}, err
+	}
+	back, err := ls.get(ls.pBack())
+	if err != nil {
+		return bounds{}, err
+	}
+	if ls.legacy(front, back) {
+		return bounds{}, fmt.Errorf("queue %q: %w", ls.ns, ErrLegacyLayout)
+	}
+
+	var b bounds
+	if front != nil {
+		if b.front, err = decodeSeq(front); err != nil {
+			return bounds{}, err
+		}
+	}
+	if back != nil {
+		if b.back, err = decodeSeq(back); err != nil {
+			return bounds{}, err
+		}
+	}
+	if b.count, err = ls.getSeq(ls.special(pCount)); err != nil {
+		return bounds{}, err
+	}
+	if b.bytes, err = ls.getSeq(ls.special(pBytes)); err != nil {
+		return bounds{}, err
+	}
+	return b, nil
+}
+
+// putBounds includes writes of the front and back sequence numbers and item count in batch
+func (ls *Queue) putBounds(batch *leveldb.Batch, b bounds) {
+	batch.Put(ls.pFront(), appendSeq(nil, b.front))
+	batch.Put(ls.pBack(), appendSeq(nil, b.back))
+	batch.Put(ls.special(pCount), appendSeq(nil, b.count))
+	batch.Put(ls.special(pBytes), appendSeq(nil, b.bytes))
+}
+
+// getSeq reads the sequence number stored under key, which is zero if the key was never written
+func (ls *Queue) getSeq(key []byte) (uint64, error) {
+	v, err := ls.get(key)
+	if err != nil {
+		return 0, err
+	}
+	if v == nil {
+		return 0, nil
+	}
+	return decodeSeq(v)
+}
+
+func decodeSeq(v []byte) (uint64, error) {
+	if len(v) != 8 {
+		return 0, fmt.Errorf("malformed sequence number of length %d: %w", len(v), leveladt.ErrCorrupt)
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+// appendSeq appends the big-endian encoding of seq to b, which sorts in numeric order
+func appendSeq(b []byte, seq uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], seq)
+	return append(b, enc[:]...)
+}
+
+// appendID appends id to b preceded by its big-endian length, so that a key ending in an
+// ID can be told apart from the keys of a queue whose namespace extends this one
+func appendID(b []byte, id string) []byte {
+	return append(appendSeq(b, uint64(len(id))), id...)
+}
+
+// parseID returns the ID that appendID encoded at offset off of key, and false if the
+// rest of key is not exactly one such ID
+func parseID(key []byte, off int) (string, bool) {
+	if len(key) < off+8 || binary.BigEndian.Uint64(key[off:]) != uint64(len(key)-off-8) {
+		return "", false
+	}
+	return string(key[off+8:]), true
 }
 
 // queueValue is a representation of a pushed queue item that can be serialized to bytes
-// and helps to support deduplication and namespacing
 type queueValue struct {
-	ns    string
-	val   string
-	nonce string
+	val      string
+	attempts uint64 // number of times the item was delivered by Receive
+	lastErr  string // reason the last delivery of the item failed
+	expires  int64  // unix time in nanoseconds at which the item expires, or zero if it never does
 }
 
+// expired returns true if the time to live of the item passed at unix time now
+func (qv queueValue) expired(now int64) bool {
+	return qv.expires != 0 && qv.expires <= now
+}
+
+// MarshalBinary encodes each field as a uvarint length followed by its raw bytes, so that
+// values containing any byte sequence round-trip unchanged. The delivery attempts and
+// expiry are encoded as plain uvarints.
 func (qv queueValue) MarshalBinary() ([]byte, error) {
-	var b bytes.Buffer
-	fmt.Fprintf(&b, "%s %s %s", qv.ns, qv.val, qv.nonce)
-	return b.Bytes(), nil
+	b := make([]byte, 0, 4*binary.MaxVarintLen64+len(qv.val)+len(qv.lastErr))
+	b = appendField(b, qv.val)
+	b = appendUvarint(b, qv.attempts)
+	b = appendField(b, qv.lastErr)
+	b = appendUvarint(b, uint64(qv.expires))
+	return b, nil
 }
 
 func (qv *queueValue) UnmarshalBinary(data []byte) error {
-	b := bytes.NewBuffer(data)
-	_, err := fmt.Fscanf(b, "%s %s %s", &qv.ns, &qv.val, &qv.nonce)
-	return err
+	var err error
+	if qv.val, data, err = readField(data); err != nil {
+		return err
+	}
+
+	var n int
+	if qv.attempts, n = binary.Uvarint(data); n <= 0 {
+		return fmt.Errorf("malformed queue value attempts: %w", leveladt.ErrCorrupt)
+	}
+	if qv.lastErr, data, err = readField(data[n:]); err != nil {
+		return err
+	}
+
+	expires, n := binary.Uvarint(data)
+	if n <= 0 {
+		return fmt.Errorf("malformed queue value expiry: %w", leveladt.ErrCorrupt)
+	}
+	qv.expires = int64(expires)
+	data = data[n:]
+	if len(data) != 0 {
+		return fmt.Errorf("%d trailing bytes after queue value: %w", len(data), leveladt.ErrCorrupt)
+	}
+	return nil
+}
+
+// valueSize returns the size of the value of an encoded queueValue, from the length
+// prefix of its first field
+func valueSize(encoded []byte) uint64 {
+	n, _ := binary.Uvarint(encoded)
+	return n
+}
+
+// appendField appends the length-prefixed field to b
+func appendField(b []byte, field string) []byte {
+	b = appendUvarint(b, uint64(len(field)))
+	return append(b, field...)
+}
+
+// appendUvarint appends the uvarint encoding of x to b
+func appendUvarint(b []byte, x uint64) []byte {
+	var enc [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(enc[:], x)
+	return append(b, enc[:n]...)
+}
+
+// readField reads a length-prefixed field from the start of b and returns it with the unread remainder of b
+func readField(b []byte) (string, []byte, error) {
+	size, n := binary.Uvarint(b)
+	if n <= 0 {
+		return "", nil, fmt.Errorf("malformed queue value field length: %w", leveladt.ErrCorrupt)
+	}
+	b = b[n:]
+	if uint64(len(b)) < size {
+		return "", nil, fmt.Errorf("queue value field of length %d truncated to %d bytes: %w", size, len(b), leveladt.ErrCorrupt)
+	}
+	return string(b[:size]), b[size:], nil
 }
 
 // encode will serialize queueValue instances
 func encode(qv queueValue) ([]byte, error) {
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	if err := enc.Encode(qv); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	return qv.MarshalBinary()
 }
 
 // decode will deserialize bytes to an instance of queueValue
 func decode(encoded []byte) (queueValue, error) {
-	dec := gob.NewDecoder(bytes.NewBuffer(encoded))
-	var frontDecoded queueValue
-	if err := dec.Decode(&frontDecoded); err != nil {
+	var decoded queueValue
+	if err := decoded.UnmarshalBinary(encoded); err != nil {
 		return queueValue{}, err
 	}
-	return frontDecoded, nil
+	return decoded, nil
 }
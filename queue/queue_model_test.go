@@ -2,9 +2,12 @@ package queue
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/commands"
@@ -29,7 +32,7 @@ func TestQueueModel(t *testing.T) {
 			return &queueController{
 				dir:   dir,
 				ldb:   db,
-				queue: NewQueue([]byte(testNamespace), db),
+				queue: newModelQueue(db),
 			}
 		},
 		InitialStateGen: gen.Const(makeQueueModel()),
@@ -37,7 +40,20 @@ func TestQueueModel(t *testing.T) {
 			return true
 		},
 		GenCommandFunc: func(st commands.State) gopter.Gen {
-			return gen.OneGenOf(genPushCommand, genPopCommand(st), genCrashCommand)
+			return gen.OneGenOf(
+				genPushCommand,
+				genPopCommand(st),
+				genPushBatchCommand,
+				genPopBatchCommand,
+				genFailedPushBatchCommand,
+				genPeekCommand,
+				genPeekBackCommand,
+				genLenCommand,
+				genReceiveCommand,
+				genAckCommand(st),
+				genNackCommand(st),
+				genCrashCommand,
+			)
 		},
 	}
 
@@ -46,10 +62,39 @@ func TestQueueModel(t *testing.T) {
 	properties.TestingRun(t)
 }
 
+// genBytes generates arbitrary byte slices, including empty slices and slices containing
+// whitespace or zero bytes
+var genBytes gopter.Gen = gen.SliceOf(gen.UInt8())
+
 func genPushCommand(params *gopter.GenParameters) *gopter.GenResult {
 	return gopter.NewGenResult(
 		pushCommand{
-			x: []byte(gen.Identifier()(params).Result.(string)),
+			x: genBytes(params).Result.([]byte),
+		},
+		gopter.NoShrinker,
+	)
+}
+
+func genPushBatchCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		pushBatchCommand{
+			xs: gen.SliceOf(genBytes)(params).Result.([][]byte),
+		},
+		gopter.NoShrinker,
+	)
+}
+
+func genPopBatchCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popBatchCommand{n: 1 + params.Rng.Intn(8)},
+		gopter.NoShrinker,
+	)
+}
+
+func genFailedPushBatchCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		failedPushBatchCommand{
+			xs: gen.SliceOf(genBytes)(params).Result.([][]byte),
 		},
 		gopter.NoShrinker,
 	)
@@ -64,6 +109,60 @@ var genPopCommand = func(st commands.State) gopter.Gen {
 	}
 }
 
+func genPeekCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		peekCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genPeekBackCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		peekBackCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genLenCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		lenCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genReceiveCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		receiveCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+var genAckCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		inflight := len(st.(queueModel).inflight)
+		if inflight == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(ackCommand{}))
+		}
+		return gopter.NewGenResult(
+			ackCommand{i: params.Rng.Intn(inflight)},
+			gopter.NoShrinker,
+		)
+	}
+}
+
+var genNackCommand = func(st commands.State) gopter.Gen {
+	return func(params *gopter.GenParameters) *gopter.GenResult {
+		inflight := len(st.(queueModel).inflight)
+		if inflight == 0 {
+			return gopter.NewEmptyResult(reflect.TypeOf(nackCommand{}))
+		}
+		return gopter.NewGenResult(
+			nackCommand{i: params.Rng.Intn(inflight)},
+			gopter.NoShrinker,
+		)
+	}
+}
+
 func genCrashCommand(params *gopter.GenParameters) *gopter.GenResult {
 	return gopter.NewGenResult(
 		crashCommand{},
@@ -96,14 +195,14 @@ func (cmd pushCommand) PreCondition(_ commands.State) bool {
 
 func (cmd pushCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
 	if e, ok := result.(error); ok {
-		return &gopter.PropResult{Error: e}
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
 	}
 
 	return gopter.NewPropResult(true, "")
 }
 
 func (cmd pushCommand) String() string {
-	return fmt.Sprintf("push(%s)", string(cmd.x))
+	return fmt.Sprintf("push(%q)", cmd.x)
 }
 
 type popCommand struct{}
@@ -125,13 +224,13 @@ func (cmd popCommand) NextState(state commands.State) commands.State {
 
 func (cmd popCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
 	if e, ok := result.(error); ok {
-		return &gopter.PropResult{Error: e}
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
 	}
 
 	got := result.([]byte)
 	want := st.(queueModel).lastPopped
 	if !bytes.Equal(got, want) {
-		return gopter.NewPropResult(false, fmt.Sprintf("%s != %s", got, want))
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
 	}
 
 	return gopter.NewPropResult(true, "")
@@ -145,6 +244,358 @@ func (cmd popCommand) String() string {
 	return "pop()"
 }
 
+type pushBatchCommand struct {
+	xs [][]byte
+}
+
+func (cmd pushBatchCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	q := sut.(*queueController).queue
+	if err := q.EnqueueBatch(cmd.xs); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd pushBatchCommand) NextState(state commands.State) commands.State {
+	st := state.(queueModel).clone()
+	st.PushBatch(cmd.xs)
+	return st
+}
+
+func (cmd pushBatchCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd pushBatchCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd pushBatchCommand) String() string {
+	return fmt.Sprintf("pushBatch(%q)", cmd.xs)
+}
+
+// popBatchCommand removes up to n items from the front of the queue
+type popBatchCommand struct {
+	n int
+}
+
+func (cmd popBatchCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	q := sut.(*queueController).queue
+	xs, err := q.DequeueBatch(cmd.n)
+	if err != nil {
+		return commands.Result(err)
+	}
+	return xs
+}
+
+func (cmd popBatchCommand) NextState(state commands.State) commands.State {
+	st := state.(queueModel).clone()
+	st.PopBatch(cmd.n)
+	return st
+}
+
+func (cmd popBatchCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([][]byte)
+	want := st.(queueModel).lastBatch
+	if !reflect.DeepEqual(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popBatchCommand) PreCondition(st commands.State) bool {
+	return st.(queueModel).size() > 0
+}
+
+func (cmd popBatchCommand) String() string {
+	return fmt.Sprintf("popBatch(%d)", cmd.n)
+}
+
+// failedPushBatchCommand attempts a batch enqueue whose write to LevelDB fails. None of
+// the items may be enqueued, neither as seen by the queue that attempted the write nor
+// by a queue reopened on the same database.
+type failedPushBatchCommand struct {
+	xs [][]byte
+}
+
+func (cmd failedPushBatchCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	qc := sut.(*queueController)
+
+	errWrite := errors.New("injected write failure")
+	write := qc.queue.writeBatch
+	qc.queue.writeBatch = func(*leveldb.Batch) error { return errWrite }
+	err := qc.queue.EnqueueBatch(cmd.xs)
+	qc.queue.writeBatch = write
+
+	if len(cmd.xs) > 0 && !errors.Is(err, errWrite) {
+		return fmt.Errorf("batch enqueue with failing write returned %v", err)
+	}
+
+	var lens []int64
+	for _, q := range []*Queue{qc.queue, newModelQueue(qc.ldb)} {
+		n, err := q.Len()
+		if err != nil {
+			return err
+		}
+		lens = append(lens, n)
+	}
+	return lens
+}
+
+func (cmd failedPushBatchCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd failedPushBatchCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	want := int64(st.(queueModel).size())
+	for _, got := range result.([]int64) {
+		if got != want {
+			return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+		}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd failedPushBatchCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd failedPushBatchCommand) String() string {
+	return fmt.Sprintf("failedPushBatch(%q)", cmd.xs)
+}
+
+type peekCommand struct{}
+
+func (cmd peekCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	q := sut.(*queueController).queue
+	front, err := q.Peek()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return front
+}
+
+func (cmd peekCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd peekCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want, _ := st.(queueModel).Peek()
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd peekCommand) PreCondition(st commands.State) bool {
+	return st.(queueModel).size() > 0
+}
+
+func (cmd peekCommand) String() string {
+	return "peek()"
+}
+
+type peekBackCommand struct{}
+
+func (cmd peekBackCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	q := sut.(*queueController).queue
+	back, err := q.PeekBack()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return back
+}
+
+func (cmd peekBackCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd peekBackCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want, _ := st.(queueModel).PeekBack()
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd peekBackCommand) PreCondition(st commands.State) bool {
+	return st.(queueModel).size() > 0
+}
+
+func (cmd peekBackCommand) String() string {
+	return "peekBack()"
+}
+
+type lenCommand struct{}
+
+func (cmd lenCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	q := sut.(*queueController).queue
+	n, err := q.Len()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return n
+}
+
+func (cmd lenCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd lenCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.(int64)
+	want := int64(st.(queueModel).size())
+	if got != want {
+		return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd lenCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd lenCommand) String() string {
+	return "len()"
+}
+
+type receiveCommand struct{}
+
+func (cmd receiveCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	qc := sut.(*queueController)
+	msg, err := qc.queue.Receive()
+	if err != nil {
+		return commands.Result(err)
+	}
+	qc.receipts = append(qc.receipts, msg.ID)
+	return msg.Value
+}
+
+func (cmd receiveCommand) NextState(state commands.State) commands.State {
+	st := state.(queueModel).clone()
+	st.Receive()
+	return st
+}
+
+func (cmd receiveCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(queueModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd receiveCommand) PreCondition(st commands.State) bool {
+	return st.(queueModel).size() > 0
+}
+
+func (cmd receiveCommand) String() string {
+	return "receive()"
+}
+
+// ackCommand acknowledges the i-th oldest message in flight
+type ackCommand struct {
+	i int
+}
+
+func (cmd ackCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	qc := sut.(*queueController)
+	if err := qc.queue.Ack(qc.receipt(cmd.i)); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd ackCommand) NextState(state commands.State) commands.State {
+	st := state.(queueModel).clone()
+	st.Ack(cmd.i)
+	return st
+}
+
+func (cmd ackCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd ackCommand) PreCondition(st commands.State) bool {
+	return cmd.i < len(st.(queueModel).inflight)
+}
+
+func (cmd ackCommand) String() string {
+	return fmt.Sprintf("ack(%d)", cmd.i)
+}
+
+// nackCommand returns the i-th oldest message in flight to the queue
+type nackCommand struct {
+	i int
+}
+
+func (cmd nackCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	qc := sut.(*queueController)
+	if err := qc.queue.Nack(qc.receipt(cmd.i)); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd nackCommand) NextState(state commands.State) commands.State {
+	st := state.(queueModel).clone()
+	st.Nack(cmd.i)
+	return st
+}
+
+func (cmd nackCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd nackCommand) PreCondition(st commands.State) bool {
+	return cmd.i < len(st.(queueModel).inflight)
+}
+
+func (cmd nackCommand) String() string {
+	return fmt.Sprintf("nack(%d)", cmd.i)
+}
+
 type crashCommand struct{}
 
 func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
@@ -160,7 +611,7 @@ func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
 	}
 
 	qc.ldb = db
-	qc.queue = NewQueue([]byte(testNamespace), db)
+	qc.queue = newModelQueue(db)
 
 	return nil
 }
@@ -171,7 +622,7 @@ func (cmd crashCommand) NextState(state commands.State) commands.State {
 
 func (cmd crashCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
 	if e, ok := result.(error); ok {
-		return &gopter.PropResult{Error: e}
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
 	}
 	return gopter.NewPropResult(true, "")
 }
@@ -187,6 +638,15 @@ func (cmd crashCommand) String() string {
 var (
 	_ commands.Command = pushCommand{}
 	_ commands.Command = popCommand{}
+	_ commands.Command = pushBatchCommand{}
+	_ commands.Command = popBatchCommand{}
+	_ commands.Command = failedPushBatchCommand{}
+	_ commands.Command = peekCommand{}
+	_ commands.Command = peekBackCommand{}
+	_ commands.Command = lenCommand{}
+	_ commands.Command = receiveCommand{}
+	_ commands.Command = ackCommand{}
+	_ commands.Command = nackCommand{}
 	_ commands.Command = crashCommand{}
 )
 
@@ -196,4 +656,23 @@ type queueController struct {
 	dir   string      // root of LevelDB database
 	ldb   *leveldb.DB // current LevelDB connection
 	queue *Queue      // queue under test
+
+	receipts []string // receipt IDs of messages in flight, in order of receipt
+}
+
+// receipt removes and returns the receipt ID of the i-th oldest message in flight
+func (qc *queueController) receipt(i int) string {
+	id := qc.receipts[i]
+	qc.receipts = append(qc.receipts[:i], qc.receipts[i+1:]...)
+	return id
+}
+
+// modelEpoch is the constant time seen by queues under test, so that no message in
+// flight is delivered again behind the back of the model
+var modelEpoch = time.Unix(1600000000, 0)
+
+func newModelQueue(db *leveldb.DB) *Queue {
+	q := NewQueue([]byte(testNamespace), db)
+	q.now = func() time.Time { return modelEpoch }
+	return q
 }
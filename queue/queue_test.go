@@ -2,12 +2,14 @@ package queue
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"testing"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
+	"github.com/lyonssp/leveladt"
 	"github.com/stretchr/testify/assert"
 	"github.com/syndtr/goleveldb/leveldb"
 )
@@ -51,6 +53,56 @@ func TestQueueProperties(t *testing.T) {
 		gen.SliceOf(gen.Identifier()),
 	))
 
+	properties.Property("arbitrary bytes are dequeued unchanged", prop.ForAll(
+		func(xs [][]byte) bool {
+			dir, err := ioutil.TempDir("", "test")
+			if err != nil {
+				return false
+			}
+			db, err := leveldb.OpenFile(dir, nil)
+			if err != nil {
+				return false
+			}
+
+			q := NewQueue([]byte("test"), db)
+
+			for _, x := range xs {
+				if err := q.Enqueue(x); err != nil {
+					return false
+				}
+			}
+
+			for _, x := range xs {
+				got, err := q.Dequeue()
+				if err != nil {
+					return false
+				}
+				if !bytes.Equal(got, x) {
+					return false
+				}
+			}
+
+			return true
+		},
+		gen.SliceOf(genBytes),
+	))
+
+	properties.Property("queue values round-trip through binary encoding", prop.ForAll(
+		func(val []byte, attempts uint64, lastErr []byte, expires int64) bool {
+			qv := queueValue{val: string(val), attempts: attempts, lastErr: string(lastErr), expires: expires}
+			encoded, err := encode(qv)
+			if err != nil {
+				return false
+			}
+			decoded, err := decode(encoded)
+			if err != nil {
+				return false
+			}
+			return decoded == qv
+		},
+		genBytes, gen.UInt64(), genBytes, gen.Int64(),
+	))
+
 	properties.TestingRun(t)
 }
 
@@ -102,6 +154,90 @@ func TestQueue(t *testing.T) {
 	})
 }
 
+func TestBatch(t *testing.T) {
+	t.Run("enqueue then dequeue", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}))
+
+		got, err := q.DequeueBatch(2)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("foo"), []byte("bar")}, got)
+
+		// a batch larger than the queue returns the remaining items
+		got, err = q.DequeueBatch(2)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("baz")}, got)
+
+		_, err = q.DequeueBatch(2)
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.EnqueueBatch(nil))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(0), n)
+	})
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		_, err := q.DequeueBatch(0)
+		assert.NotNil(err)
+	})
+}
+
+func TestPeek(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	q := NewQueue([]byte("test"), db)
+
+	empty, err := q.IsEmpty()
+	assert.Nil(err)
+	assert.True(empty)
+
+	_, err = q.Peek()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	_, err = q.PeekBack()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	_, err = q.Dequeue()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	assert.Nil(q.Enqueue([]byte("foo")))
+	assert.Nil(q.Enqueue([]byte("bar")))
+
+	front, err := q.Peek()
+	assert.Nil(err)
+	assert.Equal([]byte("foo"), front)
+
+	back, err := q.PeekBack()
+	assert.Nil(err)
+	assert.Equal([]byte("bar"), back)
+
+	n, err := q.Len()
+	assert.Nil(err)
+	assert.Equal(int64(2), n)
+
+	empty, err = q.IsEmpty()
+	assert.Nil(err)
+	assert.False(empty)
+}
+
 func TestNamespacing(t *testing.T) {
 	assert := assert.New(t)
 
@@ -129,6 +265,18 @@ func TestNamespacing(t *testing.T) {
 	assert.Nil(err)
 }
 
+func TestDecodeTruncated(t *testing.T) {
+	assert := assert.New(t)
+
+	encoded, err := encode(queueValue{val: "foo bar\n", attempts: 300, lastErr: "boom", expires: 1 << 40})
+	assert.Nil(err)
+
+	for i := 0; i < len(encoded); i++ {
+		_, err := decode(encoded[:i])
+		assert.True(errors.Is(err, leveladt.ErrCorrupt))
+	}
+}
+
 // Capture failed model test sequences
 func TestRegressions(t *testing.T) {
 	assert := assert.New(t)
@@ -0,0 +1,297 @@
+package queue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// prefixes of the keyspaces that hold received but unacknowledged messages
+const (
+	pInflight = "inflight" // followed by a length-prefixed receipt ID, holds the leased item
+	pLease    = "lease"    // followed by the big-endian lease deadline and a length-prefixed receipt ID, indexes leases by deadline
+)
+
+// reasons recorded for failed deliveries that were not given one
+const (
+	errVisibilityTimeout = "visibility timeout expired"
+	errNack              = "negatively acknowledged"
+)
+
+// Message is an item received from a queue. The item stays in flight until it is
+// acknowledged with Ack, returned to the queue with Nack, or its deadline passes, after
+// which it is delivered again.
+type Message struct {
+	ID       string    // receipt ID that acknowledges the delivery
+	Value    []byte    // item as it was enqueued
+	Deadline time.Time // time after which the message is delivered again
+	Attempts int       // number of times the item was delivered, including this delivery
+}
+
+// Receive returns the item at the front of the queue, moving it in flight instead of
+// removing it. Messages whose visibility timeout has passed without an Ack are delivered
+// again before new items.
+func (ls *Queue) Receive() (*Message, error) {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	now := ls.now()
+
+	// redeliver the oldest expired lease, if any, dead-lettering leases out of deliveries
+	for {
+		id, ok, err := ls.expiredLease(now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		l, err := ls.lease(id)
+		if err != nil {
+			return nil, err
+		}
+
+		batch := new(leveldb.Batch)
+		ls.release(batch, id, l)
+		l.qv.lastErr = errVisibilityTimeout
+		switch {
+		case l.qv.expired(now.UnixNano()):
+			err = ls.commit(batch, []queueValue{l.qv})
+		case ls.exhausted(l.qv):
+			err = ls.kill(batch, l.qv)
+		default:
+			msg, err := ls.deliver(batch, l.qv, now)
+			if err != nil {
+				return nil, err
+			}
+			if err := ls.write(batch); err != nil {
+				return nil, err
+			}
+			return msg, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ls.promote(); err != nil {
+		return nil, err
+	}
+
+	b, err := ls.bounds()
+	if err != nil {
+		return nil, err
+	}
+	if b.empty() {
+		return nil, fmt.Errorf("cannot receive from empty queue: %w", leveladt.ErrEmpty)
+	}
+
+	// move the front item in flight, purge expired items before it, and advance the front
+	// pointer past them in one batch
+	batch := new(leveldb.Batch)
+	qvs, expired, err := ls.pop(batch, &b, 1)
+	if err != nil {
+		return nil, err
+	}
+	ls.putBounds(batch, b)
+
+	if len(qvs) == 0 {
+		if err := ls.commit(batch, expired); err != nil {
+			return nil, err
+		}
+		ls.freed()
+		return nil, fmt.Errorf("cannot receive from empty queue: %w", leveladt.ErrEmpty)
+	}
+
+	msg, err := ls.deliver(batch, qvs[0], now)
+	if err != nil {
+		return nil, err
+	}
+	if err := ls.commit(batch, expired); err != nil {
+		return nil, err
+	}
+	ls.freed()
+	return msg, nil
+}
+
+// Ack acknowledges the message with receipt ID id, removing it from the queue for good.
+// Ack returns an error wrapping leveladt.ErrNotFound if the message is not in flight
+// under id, for example because it was acknowledged before or delivered again.
+func (ls *Queue) Ack(id string) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	l, err := ls.lease(id)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	ls.release(batch, id, l)
+	return ls.write(batch)
+}
+
+// Nack returns the message with receipt ID id to the back of the queue, so that it is
+// delivered again. Nack returns an error wrapping leveladt.ErrNotFound if the message is
+// not in flight under id.
+func (ls *Queue) Nack(id string) error {
+	return ls.NackWithError(id, nil)
+}
+
+// NackWithError is Nack recording cause as the reason the delivery failed. If the message
+// was delivered MaxDeliveries times, it is moved to the dead-letter queue instead of the
+// back of the queue, with cause attached.
+func (ls *Queue) NackWithError(id string, cause error) error {
+	ls.l.Lock()
+	defer ls.l.Unlock()
+
+	l, err := ls.lease(id)
+	if err != nil {
+		return err
+	}
+
+	l.qv.lastErr = errNack
+	if cause != nil {
+		l.qv.lastErr = cause.Error()
+	}
+
+	batch := new(leveldb.Batch)
+	ls.release(batch, id, l)
+	if ls.exhausted(l.qv) {
+		return ls.kill(batch, l.qv)
+	}
+
+	b, err := ls.bounds()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encode(l.qv)
+	if err != nil {
+		return err
+	}
+
+	ls.push(batch, &b, encoded)
+	ls.putBounds(batch, b)
+	if err := ls.write(batch); err != nil {
+		return err
+	}
+
+	ls.notify(1)
+	return nil
+}
+
+// deliver includes the lease of qv under a new receipt ID in batch and returns the
+// leased message. The caller must write the batch.
+func (ls *Queue) deliver(batch *leveldb.Batch, qv queueValue, now time.Time) (*Message, error) {
+	qv.attempts++
+	l := lease{
+		deadline: now.Add(ls.o.VisibilityTimeout).UnixNano(),
+		qv:       qv,
+	}
+	id := uuid.NewString()
+
+	encoded, err := encodeLease(l)
+	if err != nil {
+		return nil, err
+	}
+	batch.Put(ls.inflight(id), encoded)
+	batch.Put(ls.leaseIndex(l.deadline, id), nil)
+
+	return &Message{
+		ID:       id,
+		Value:    []byte(qv.val),
+		Deadline: time.Unix(0, l.deadline),
+		Attempts: int(qv.attempts),
+	}, nil
+}
+
+// exhausted returns true if qv was delivered as many times as the queue allows
+func (ls *Queue) exhausted(qv queueValue) bool {
+	return ls.o.MaxDeliveries > 0 && qv.attempts >= uint64(ls.o.MaxDeliveries)
+}
+
+// release includes the removal of the lease l with receipt ID id in batch
+func (ls *Queue) release(batch *leveldb.Batch, id string, l lease) {
+	batch.Delete(ls.inflight(id))
+	batch.Delete(ls.leaseIndex(l.deadline, id))
+}
+
+// lease reads the in flight message with receipt ID id
+func (ls *Queue) lease(id string) (lease, error) {
+	encoded, err := ls.get(ls.inflight(id))
+	if err != nil {
+		return lease{}, err
+	}
+	if encoded == nil {
+		return lease{}, fmt.Errorf("no message in flight with receipt %q: %w", id, leveladt.ErrNotFound)
+	}
+	return decodeLease(encoded)
+}
+
+// expiredLease returns the receipt ID of the lease with the earliest deadline, if that
+// deadline is not after now
+func (ls *Queue) expiredLease(now time.Time) (string, bool, error) {
+	prefix := ls.special(pLease)
+	iter := ls.ldb.NewIterator(&util.Range{
+		Start: prefix,
+		Limit: appendSeq(prefix, uint64(now.UnixNano())+1),
+	}, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if id, ok := parseID(iter.Key(), len(prefix)+8); ok {
+			return id, true, nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return "", false, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return "", false, nil
+}
+
+// inflight encodes the key of the in flight message with receipt ID id
+func (ls *Queue) inflight(id string) []byte {
+	return appendID(ls.special(pInflight), id)
+}
+
+// leaseIndex encodes the key that indexes the lease with receipt ID id by its deadline
+func (ls *Queue) leaseIndex(deadline int64, id string) []byte {
+	return appendID(appendSeq(ls.special(pLease), uint64(deadline)), id)
+}
+
+// lease is the record of an in flight message
+type lease struct {
+	deadline int64 // unix time in nanoseconds after which the message is delivered again
+	qv       queueValue
+}
+
+// encodeLease serializes the lease deadline followed by the leased queueValue
+func encodeLease(l lease) ([]byte, error) {
+	encoded, err := encode(l.qv)
+	if err != nil {
+		return nil, err
+	}
+	return append(appendSeq(nil, uint64(l.deadline)), encoded...), nil
+}
+
+// decodeLease deserializes bytes to an instance of lease
+func decodeLease(encoded []byte) (lease, error) {
+	if len(encoded) < 8 {
+		return lease{}, fmt.Errorf("lease of length %d truncated: %w", len(encoded), leveladt.ErrCorrupt)
+	}
+	qv, err := decode(encoded[8:])
+	if err != nil {
+		return lease{}, err
+	}
+	return lease{
+		deadline: int64(binary.BigEndian.Uint64(encoded)),
+		qv:       qv,
+	}, nil
+}
@@ -0,0 +1,202 @@
+package queue
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// testClock is a manually advanced clock for deterministic tests
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.t
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Unix(1600000000, 0)}
+}
+
+func TestReceive(t *testing.T) {
+	t.Run("ack removes message", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+		assert.Equal(clock.Now().Add(DefaultVisibilityTimeout), msg.Deadline)
+
+		// in flight messages are not part of the queue
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+
+		assert.Nil(q.Ack(msg.ID))
+
+		// acknowledging twice fails
+		err = q.Ack(msg.ID)
+		assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+		// the message is not delivered again after its deadline
+		clock.Advance(2 * DefaultVisibilityTimeout)
+		msg, err = q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), msg.Value)
+	})
+
+	t.Run("nack requeues message", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Nil(q.Nack(msg.ID))
+
+		err = q.Ack(msg.ID)
+		assert.True(errors.Is(err, leveladt.ErrNotFound))
+
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+
+		got, err = q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("expired lease is delivered again", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		first, err := q.Receive()
+		assert.Nil(err)
+
+		clock.Advance(DefaultVisibilityTimeout)
+
+		again, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), again.Value)
+		assert.NotEqual(first.ID, again.ID)
+
+		// the receipt of the expired delivery is no longer valid
+		err = q.Ack(first.ID)
+		assert.True(errors.Is(err, leveladt.ErrNotFound))
+		assert.Nil(q.Ack(again.ID))
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), msg.Value)
+	})
+
+	t.Run("failed redelivery", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		_, err := q.Receive()
+		assert.Nil(err)
+
+		clock.Advance(DefaultVisibilityTimeout)
+
+		// a delivery that was not written must not be handed out
+		errWrite := errors.New("injected write failure")
+		q.writeBatch = func(*leveldb.Batch) error { return errWrite }
+		msg, err := q.Receive()
+		assert.True(errors.Is(err, errWrite))
+		assert.Nil(msg)
+	})
+
+	t.Run("visibility timeout option", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q := NewQueueWithOptions([]byte("test"), db, &Options{VisibilityTimeout: time.Minute})
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal(clock.Now().Add(time.Minute), msg.Deadline)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		_, err := q.Receive()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		err = q.Nack("missing")
+		assert.True(errors.Is(err, leveladt.ErrNotFound))
+	})
+
+	t.Run("crash before ack", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q := NewQueue([]byte("test"), db)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		_, err = q.Receive()
+		assert.Nil(err)
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q = NewQueue([]byte("test"), db)
+		q.now = clock.Now
+
+		_, err = q.Receive()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		clock.Advance(DefaultVisibilityTimeout)
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+	})
+}
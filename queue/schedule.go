@@ -0,0 +1,238 @@
+package queue
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// special key and prefix of the keyspace that holds items scheduled for later delivery
+const (
+	pSchedSeq = "nextsched" // sequence number the next scheduled item will be stored under
+	pSched    = "sched"     // followed by the big-endian due time and sequence number of a scheduled item
+)
+
+// EnqueueAt enqueues v to the back of the queue at time t. Until then, the item is held
+// in a schedule ordered by due time and is not visible to Dequeue, Receive, Peek or Len.
+// Items due at the same time are enqueued in the order they were scheduled. If t is not
+// in the future, EnqueueAt is equivalent to Enqueue. The time to live of a scheduled
+// item starts when it is due.
+func (ls *Queue) EnqueueAt(t time.Time, v []byte, opts ...EnqueueOption) error {
+	return ls.admit(context.Background(), 1, uint64(len(v)), func() error {
+		return ls.enqueueAt(new(leveldb.Batch), t, v, opts)
+	})
+}
+
+// EnqueueAfter enqueues v to the back of the queue once d has elapsed, like EnqueueAt
+func (ls *Queue) EnqueueAfter(d time.Duration, v []byte, opts ...EnqueueOption) error {
+	t := ls.now().Add(d)
+	return ls.admit(context.Background(), 1, uint64(len(v)), func() error {
+		return ls.enqueueAt(new(leveldb.Batch), t, v, opts)
+	})
+}
+
+// enqueueAt enqueues v at time t, writing batch along with it. Capacity is only checked
+// if v is enqueued right away. The caller must hold the queue lock.
+func (ls *Queue) enqueueAt(batch *leveldb.Batch, t time.Time, v []byte, opts []EnqueueOption) error {
+	now := ls.now()
+	if t.Before(now) {
+		t = now
+	}
+
+	encoded, err := encode(makeEnqueueOptions(opts).value(v, t))
+	if err != nil {
+		return err
+	}
+
+	if !t.After(now) {
+		b, err := ls.bounds()
+		if err != nil {
+			return err
+		}
+		expired, err := ls.makeRoom(batch, &b, 1, uint64(len(v)))
+		if err != nil {
+			return err
+		}
+		ls.push(batch, &b, encoded)
+		ls.putBounds(batch, b)
+		if err := ls.commit(batch, expired); err != nil {
+			return err
+		}
+
+		ls.notify(1)
+		return nil
+	}
+
+	seq, err := ls.getSeq(ls.special(pSchedSeq))
+	if err != nil {
+		return err
+	}
+	batch.Put(ls.scheduled(t.UnixNano(), seq), encoded)
+	batch.Put(ls.special(pSchedSeq), appendSeq(nil, seq+1))
+	if err := ls.write(batch); err != nil {
+		return err
+	}
+	if ls.dueKnown && t.UnixNano() < ls.earliest {
+		ls.earliest = t.UnixNano()
+	}
+
+	// blocked consumers need to learn about the new due time
+	if ls.schedCh != nil {
+		close(ls.schedCh)
+		ls.schedCh = nil
+	}
+	return nil
+}
+
+// promote moves every scheduled item that is due to the back of the queue in one batch.
+// The caller must hold the queue lock.
+func (ls *Queue) promote() error {
+	var (
+		b     bounds
+		batch *leveldb.Batch
+		n     int
+	)
+	err := ls.scanDue(false, func(key, encoded []byte) (bool, error) {
+		if batch == nil {
+			var err error
+			if b, err = ls.bounds(); err != nil {
+				return false, err
+			}
+			batch = new(leveldb.Batch)
+		}
+		batch.Delete(key)
+		ls.push(batch, &b, encoded)
+		n++
+		return true, nil
+	})
+	if err != nil || batch == nil {
+		return err
+	}
+
+	ls.putBounds(batch, b)
+	if err := ls.write(batch); err != nil {
+		return err
+	}
+	ls.dueKnown = false
+
+	ls.notify(n)
+	return nil
+}
+
+// scanDue calls fn with the key and encoded queueValue of every scheduled item that is
+// due, in the order promote enqueues them or in reverse if backward is set, until fn
+// returns false. Nothing is read from the database while no item is due. The caller must
+// hold the queue lock.
+func (ls *Queue) scanDue(backward bool, fn func(key, encoded []byte) (bool, error)) error {
+	now := ls.now().UnixNano()
+	earliest, err := ls.earliestDue()
+	if err != nil || earliest > now {
+		return err
+	}
+
+	prefix := ls.special(pSched)
+	iter := ls.ldb.NewIterator(&util.Range{
+		Start: prefix,
+		Limit: appendSeq(prefix, uint64(now)+1),
+	}, nil)
+	defer iter.Release()
+
+	first, step := iter.First, iter.Next
+	if backward {
+		first, step = iter.Last, iter.Prev
+	}
+	for ok := first(); ok; ok = step() {
+		if !shaped(iter.Key(), prefix, 16) {
+			continue
+		}
+		more, err := fn(append([]byte{}, iter.Key()...), append([]byte{}, iter.Value()...))
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return nil
+}
+
+// peekDue returns the encoded queueValue of the first scheduled item that is due and has
+// not expired, or of the last one if backward is set. The caller must hold the queue lock.
+func (ls *Queue) peekDue(backward bool) ([]byte, error) {
+	now := ls.now().UnixNano()
+
+	var encoded []byte
+	err := ls.scanDue(backward, func(_, v []byte) (bool, error) {
+		decoded, err := decode(v)
+		if err != nil {
+			return false, err
+		}
+		if decoded.expired(now) {
+			return true, nil
+		}
+		encoded = v
+		return false, nil
+	})
+	return encoded, err
+}
+
+// earliestDue returns the earliest due time of a scheduled item in unix nanoseconds, or
+// math.MaxInt64 if no item is scheduled, reading the schedule only if the time is not
+// known. The caller must hold the queue lock.
+func (ls *Queue) earliestDue() (int64, error) {
+	if ls.dueKnown {
+		return ls.earliest, nil
+	}
+
+	next, ok, err := ls.nextDue()
+	if err != nil {
+		return 0, err
+	}
+	ls.earliest = math.MaxInt64
+	if ok {
+		ls.earliest = next.UnixNano()
+	}
+	ls.dueKnown = true
+	return ls.earliest, nil
+}
+
+// scheduleChanged returns a channel that is closed the next time an item is scheduled.
+// The caller must hold the queue lock.
+func (ls *Queue) scheduleChanged() <-chan struct{} {
+	if ls.schedCh == nil {
+		ls.schedCh = make(chan struct{})
+	}
+	return ls.schedCh
+}
+
+// nextDue returns the due time of the earliest scheduled item, if there is one. The
+// caller must hold the queue lock.
+func (ls *Queue) nextDue() (time.Time, bool, error) {
+	prefix := ls.special(pSched)
+	iter := ls.ldb.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if shaped(iter.Key(), prefix, 16) {
+			due := int64(binary.BigEndian.Uint64(iter.Key()[len(prefix):]))
+			return time.Unix(0, due), true, nil
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return time.Time{}, false, fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return time.Time{}, false, nil
+}
+
+// scheduled encodes the key of the scheduled item with the given due time and sequence number
+func (ls *Queue) scheduled(due int64, seq uint64) []byte {
+	return appendSeq(appendSeq(ls.special(pSched), uint64(due)), seq)
+}
@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestEnqueueAt(t *testing.T) {
+	t.Run("reads do not move due items", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("bar")))
+		assert.Nil(q.EnqueueAfter(time.Hour, []byte("baz")))
+
+		clock.Advance(time.Minute)
+
+		// Len reads the bounds, so the due item counts once a write moves it
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(1), n)
+
+		got, err := q.Peek()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+
+		got, err = q.PeekBack()
+		assert.Nil(err)
+		assert.Equal([]byte("bar"), got)
+
+		items, err := q.Range(0, 0)
+		assert.Nil(err)
+		assert.Equal([]Item{{Seq: 0, Value: []byte("foo")}, {Seq: 1, Value: []byte("bar")}}, items)
+
+		// the due item is still in the schedule, and the queue still holds one item
+		b, err := q.bounds()
+		assert.Nil(err)
+		assert.Equal(uint64(1), b.count)
+		_, err = q.ldb.Get(q.scheduled(clock.Now().UnixNano(), 0), nil)
+		assert.Nil(err)
+
+		for _, want := range []string{"foo", "bar"} {
+			got, err := q.Dequeue()
+			assert.Nil(err)
+			assert.Equal([]byte(want), got)
+		}
+
+		_, err = q.Peek()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+	})
+
+	t.Run("due item behind an empty queue", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("foo")))
+		clock.Advance(time.Minute)
+
+		got, err := q.Peek()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+
+		empty, err := q.IsEmpty()
+		assert.Nil(err)
+		assert.False(empty)
+	})
+
+	t.Run("hidden until due", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("foo")))
+
+		_, err := q.Dequeue()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		_, err = q.Peek()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		n, err := q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(0), n)
+
+		clock.Advance(time.Minute)
+
+		empty, err := q.IsEmpty()
+		assert.Nil(err)
+		assert.False(empty)
+
+		// enqueueing moves the due item in ahead of the new one
+		assert.Nil(q.Enqueue([]byte("bar")))
+
+		n, err = q.Len()
+		assert.Nil(err)
+		assert.Equal(int64(2), n)
+
+		for _, want := range []string{"foo", "bar"} {
+			got, err := q.Dequeue()
+			assert.Nil(err)
+			assert.Equal([]byte(want), got)
+		}
+	})
+
+	t.Run("ordered by due time", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(2*time.Minute, []byte("late")))
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("early")))
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("early again")))
+		assert.Nil(q.Enqueue([]byte("now")))
+
+		// items in the past are enqueued immediately
+		assert.Nil(q.EnqueueAt(clock.Now().Add(-time.Minute), []byte("past")))
+
+		clock.Advance(2 * time.Minute)
+
+		for _, want := range []string{"now", "past", "early", "early again", "late"} {
+			got, err := q.Dequeue()
+			assert.Nil(err)
+			assert.Equal([]byte(want), got)
+		}
+	})
+
+	t.Run("received when due", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("foo")))
+
+		_, err := q.Receive()
+		assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+		clock.Advance(time.Minute)
+
+		msg, err := q.Receive()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), msg.Value)
+	})
+
+	t.Run("survives restart", func(t *testing.T) {
+		assert := assert.New(t)
+
+		dir, err := ioutil.TempDir("", "test")
+		assert.Nil(err)
+
+		db, err := leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		clock := newTestClock()
+		q := NewQueue([]byte("test"), db)
+		q.now = clock.Now
+
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("foo")))
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("bar")))
+
+		assert.Nil(db.Close())
+		db, err = leveldb.OpenFile(dir, nil)
+		assert.Nil(err)
+
+		q = NewQueue([]byte("test"), db)
+		q.now = clock.Now
+
+		// scheduling after a restart continues the order of earlier schedules
+		assert.Nil(q.EnqueueAfter(time.Minute, []byte("baz")))
+
+		clock.Advance(time.Minute)
+
+		for _, want := range []string{"foo", "bar", "baz"} {
+			got, err := q.Dequeue()
+			assert.Nil(err)
+			assert.Equal([]byte(want), got)
+		}
+	})
+
+	t.Run("wakes blocked consumer", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		result := make(chan []byte)
+		go func() {
+			got, err := q.DequeueWait(context.Background())
+			assert.Nil(err)
+			result <- got
+		}()
+
+		waitForWaiters(q, 1)
+		assert.Nil(q.EnqueueAfter(20*time.Millisecond, []byte("foo")))
+
+		select {
+		case got := <-result:
+			assert.Equal([]byte("foo"), got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("consumer was not woken when the item fell due")
+		}
+	})
+}
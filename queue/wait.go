@@ -0,0 +1,161 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lyonssp/leveladt"
+)
+
+// DequeueWait removes and returns the item at the front of the queue, blocking until an
+// item is enqueued if the queue is empty. Consumers blocked on the same Queue are woken
+// in the order they started waiting. DequeueWait returns the error of ctx if ctx is done
+// before an item arrives.
+func (ls *Queue) DequeueWait(ctx context.Context) ([]byte, error) {
+	vs, err := ls.awaitTake(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+	return vs[0], nil
+}
+
+// DequeueN removes and returns up to max items from the front of the queue as soon as at
+// least one item is available. If the queue stays empty for the wait duration, DequeueN
+// returns an empty batch. DequeueN returns the error of ctx if ctx is done first.
+func (ls *Queue) DequeueN(ctx context.Context, max int, wait time.Duration) ([][]byte, error) {
+	if max <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", max)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	vs, err := ls.awaitTake(waitCtx, max)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return [][]byte{}, nil
+	}
+	return vs, err
+}
+
+// awaitTake blocks until it removes at least one item that has not expired, and returns
+// up to max items. Expired items count in the bounds of the queue, so a queue that only
+// holds expired items wakes await, is purged by take, and is waited on again.
+func (ls *Queue) awaitTake(ctx context.Context, max int) ([][]byte, error) {
+	for {
+		if err := ls.await(ctx); err != nil {
+			return nil, err
+		}
+
+		vs, err := ls.take(max)
+		ls.l.Unlock()
+		if !errors.Is(err, leveladt.ErrEmpty) {
+			return vs, err
+		}
+	}
+}
+
+// await blocks until the queue holds at least one item, promoting scheduled items as they
+// fall due. It returns with the queue lock held, unless it returns an error.
+func (ls *Queue) await(ctx context.Context) error {
+	ls.l.Lock()
+
+	var w chan struct{}
+	woken := false
+	for {
+		if err := ls.promote(); err != nil {
+			ls.leave(w)
+			ls.l.Unlock()
+			return err
+		}
+
+		b, err := ls.bounds()
+		if err != nil {
+			ls.leave(w)
+			ls.l.Unlock()
+			return err
+		}
+		if !b.empty() {
+			ls.leave(w)
+			return nil
+		}
+
+		// a consumer that was woken but lost the item to another consumer keeps its place at the front of the line
+		if w == nil {
+			w = make(chan struct{}, 1)
+			if woken {
+				ls.waiters = append([]chan struct{}{w}, ls.waiters...)
+			} else {
+				ls.waiters = append(ls.waiters, w)
+			}
+		}
+
+		// wake up when the earliest scheduled item falls due, or when an earlier one is scheduled
+		var (
+			timer *time.Timer
+			due   <-chan time.Time
+		)
+		next, err := ls.earliestDue()
+		if err != nil {
+			ls.leave(w)
+			ls.l.Unlock()
+			return err
+		}
+		if next != math.MaxInt64 {
+			timer = time.NewTimer(time.Unix(0, next).Sub(ls.now()))
+			due = timer.C
+		}
+		changed := ls.scheduleChanged()
+		ls.l.Unlock()
+
+		select {
+		case <-w:
+			ls.l.Lock()
+			w = nil
+			woken = true
+		case <-due:
+			ls.l.Lock()
+		case <-changed:
+			ls.l.Lock()
+		case <-ctx.Done():
+			ls.l.Lock()
+			ls.leave(w)
+			ls.l.Unlock()
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// notify wakes up to n blocked consumers in the order they started waiting. The caller
+// must hold the queue lock.
+func (ls *Queue) notify(n int) {
+	for ; n > 0 && len(ls.waiters) > 0; n-- {
+		ls.waiters[0] <- struct{}{}
+		ls.waiters = ls.waiters[1:]
+	}
+}
+
+// leave removes the waiter w once it stops waiting. If w was woken in the meantime, the
+// wake up is passed on to the next consumer in line. A nil w is ignored. The caller must
+// hold the queue lock.
+func (ls *Queue) leave(w chan struct{}) {
+	if w == nil {
+		return
+	}
+	for i, waiter := range ls.waiters {
+		if waiter == w {
+			ls.waiters = append(ls.waiters[:i], ls.waiters[i+1:]...)
+			return
+		}
+	}
+	ls.notify(1)
+}
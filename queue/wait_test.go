@@ -0,0 +1,220 @@
+package queue
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestDequeueWait(t *testing.T) {
+	t.Run("returns immediately when not empty", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+
+		got, err := q.DequeueWait(context.Background())
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("blocks until enqueue", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		result := make(chan []byte)
+		go func() {
+			got, err := q.DequeueWait(context.Background())
+			assert.Nil(err)
+			result <- got
+		}()
+
+		waitForWaiters(q, 1)
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Equal([]byte("foo"), <-result)
+	})
+
+	t.Run("wakes waiters in order", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		first := make(chan []byte)
+		go func() {
+			got, err := q.DequeueWait(context.Background())
+			assert.Nil(err)
+			first <- got
+		}()
+		waitForWaiters(q, 1)
+
+		second := make(chan []byte)
+		go func() {
+			got, err := q.DequeueWait(context.Background())
+			assert.Nil(err)
+			second <- got
+		}()
+		waitForWaiters(q, 2)
+
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Equal([]byte("foo"), <-first)
+
+		assert.Nil(q.Enqueue([]byte("bar")))
+		assert.Equal([]byte("bar"), <-second)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := q.DequeueWait(ctx)
+		assert.Equal(context.DeadlineExceeded, err)
+		assert.Empty(q.waiters)
+
+		// the item must remain for the next consumer
+		assert.Nil(q.Enqueue([]byte("foo")))
+		got, err := q.Dequeue()
+		assert.Nil(err)
+		assert.Equal([]byte("foo"), got)
+	})
+
+	t.Run("keeps waiting when only expired items are left", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Second)))
+		clock.Advance(time.Minute)
+
+		empty, err := q.IsEmpty()
+		assert.Nil(err)
+		assert.True(empty)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = q.DequeueWait(ctx)
+		assert.Equal(context.DeadlineExceeded, err)
+
+		result := make(chan []byte)
+		go func() {
+			got, err := q.DequeueWait(context.Background())
+			assert.Nil(err)
+			result <- got
+		}()
+
+		waitForWaiters(q, 1)
+		assert.Nil(q.Enqueue([]byte("bar")))
+		assert.Equal([]byte("bar"), <-result)
+	})
+}
+
+func TestDequeueN(t *testing.T) {
+	t.Run("returns available items up to max", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		for _, x := range []string{"foo", "bar", "baz"} {
+			assert.Nil(q.Enqueue([]byte(x)))
+		}
+
+		got, err := q.DequeueN(context.Background(), 2, time.Second)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("foo"), []byte("bar")}, got)
+
+		got, err = q.DequeueN(context.Background(), 2, time.Second)
+		assert.Nil(err)
+		assert.Equal([][]byte{[]byte("baz")}, got)
+	})
+
+	t.Run("wait expires", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		got, err := q.DequeueN(context.Background(), 2, 10*time.Millisecond)
+		assert.Nil(err)
+		assert.Empty(got)
+	})
+
+	t.Run("blocks until enqueue", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		result := make(chan [][]byte)
+		go func() {
+			got, err := q.DequeueN(context.Background(), 2, time.Minute)
+			assert.Nil(err)
+			result <- got
+		}()
+
+		waitForWaiters(q, 1)
+		assert.Nil(q.Enqueue([]byte("foo")))
+		assert.Equal([][]byte{[]byte("foo")}, <-result)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := q.DequeueN(ctx, 2, time.Minute)
+		assert.Equal(context.Canceled, err)
+	})
+
+	t.Run("keeps waiting when only expired items are left", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+		clock := newTestClock()
+		q.now = clock.Now
+
+		assert.Nil(q.Enqueue([]byte("foo"), WithTTL(time.Second)))
+		clock.Advance(time.Minute)
+
+		got, err := q.DequeueN(context.Background(), 2, 10*time.Millisecond)
+		assert.Nil(err)
+		assert.Empty(got)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		assert := assert.New(t)
+		q := openTestQueue(t)
+
+		_, err := q.DequeueN(context.Background(), 0, time.Minute)
+		assert.NotNil(err)
+	})
+}
+
+func openTestQueue(t *testing.T) *Queue {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewQueue([]byte("test"), db)
+}
+
+// waitForWaiters blocks until n consumers are waiting on q
+func waitForWaiters(q *Queue, n int) {
+	for {
+		q.l.Lock()
+		waiting := len(q.waiters)
+		q.l.Unlock()
+
+		if waiting >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,66 @@
+package set
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Migrate rewrites a set stored under namespace ns by an earlier release, whose members
+// were keys directly under the namespace, to the current layout, where the namespace is
+// preceded by its length and members follow the "member" prefix next to a counter of
+// members. Sets in the current layout are left untouched, so Migrate is safe to call on
+// every start.
+//
+// Migrate must run before the set is opened with NewSet, and while no other process
+// writes to the namespace. The legacy layout did not delimit members from the namespace,
+// so Migrate takes every key under ns that holds an empty value for a member, including
+// the keys of other structures whose namespace extends ns, such as a set named
+// "usermembership" next to a set named "user". The namespaces of such structures must be
+// passed as nested for their keys to be left in place; keys holding values are left in
+// place regardless.
+func Migrate(ns []byte, ldb *leveldb.DB, nested ...[]byte) error {
+	s := NewSet(ns, ldb)
+
+	if _, err := ldb.Get(s.special(pCount), nil); err == nil {
+		return nil
+	} else if err != leveldb.ErrNotFound {
+		return fmt.Errorf("leveldb get: %w", err)
+	}
+
+	iter := ldb.NewIterator(util.BytesPrefix(ns), nil)
+	defer iter.Release()
+
+	var members [][]byte
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		if len(iter.Value()) > 0 || isNested(iter.Key(), nested) {
+			continue
+		}
+		members = append(members, append([]byte{}, iter.Key()[len(ns):]...))
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("leveldb iterate: %w", err)
+	}
+
+	// batch operations apply in order, so members are written after every legacy key is
+	// deleted
+	for _, x := range members {
+		batch.Put(s.key(x), []byte{})
+	}
+	batch.Put(s.special(pCount), encodeCount(int64(len(members))))
+	return s.write(batch)
+}
+
+// isNested returns true if key falls under any of the namespaces in nested
+func isNested(key []byte, nested [][]byte) bool {
+	for _, ns := range nested {
+		if bytes.HasPrefix(key, ns) {
+			return true
+		}
+	}
+	return false
+}
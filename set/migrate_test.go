@@ -0,0 +1,110 @@
+package set
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestMigrate(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// members stored the way earlier releases did, including members named like the keys
+	// of the current layout, next to a key of another structure
+	ns := []byte("test")
+	batch := new(leveldb.Batch)
+	for _, x := range []string{"foo", pCount, pMember + "bar"} {
+		batch.Put(append(append([]byte{}, ns...), x...), []byte{})
+	}
+	batch.Put([]byte("testlist"), []byte("value"))
+	assert.Nil(db.Write(batch, nil))
+
+	assert.Nil(Migrate(ns, db))
+
+	// migrating twice must not reinterpret current keys as legacy keys
+	assert.Nil(Migrate(ns, db))
+
+	s := NewSet(ns, db)
+	members, err := s.Members()
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte(pCount), []byte("foo"), []byte(pMember + "bar")}, members)
+
+	n, err := s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(3), n)
+
+	assert.Nil(s.Add([]byte("baz")))
+	n, err = s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(4), n)
+
+	_, err = db.Get([]byte("testfoo"), nil)
+	assert.Equal(leveldb.ErrNotFound, err)
+
+	v, err := db.Get([]byte("testlist"), nil)
+	assert.Nil(err)
+	assert.Equal([]byte("value"), v)
+}
+
+func TestMigrateNested(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// a legacy set next to a legacy set and a capped list entry holding an empty item,
+	// whose namespaces extend its own
+	batch := new(leveldb.Batch)
+	for _, key := range []string{"useralice", "usermembershipgold", "userlogentry\x00\x00\x00\x00\x00\x00\x00\x00"} {
+		batch.Put([]byte(key), []byte{})
+	}
+	assert.Nil(db.Write(batch, nil))
+
+	assert.Nil(Migrate([]byte("user"), db, []byte("usermembership"), []byte("userlog")))
+	assert.Nil(Migrate([]byte("usermembership"), db))
+
+	user := NewSet([]byte("user"), db)
+	members, err := user.Members()
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("alice")}, members)
+
+	membership := NewSet([]byte("usermembership"), db)
+	members, err = membership.Members()
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("gold")}, members)
+
+	_, err = db.Get([]byte("userlogentry\x00\x00\x00\x00\x00\x00\x00\x00"), nil)
+	assert.Nil(err)
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s := NewSet([]byte("test"), db)
+	assert.Nil(s.Add([]byte("foo")))
+	assert.Nil(s.Remove([]byte("foo")))
+
+	// an emptied set keeps its counter, so it is not taken for a legacy set
+	assert.Nil(Migrate([]byte("test"), db))
+
+	members, err := s.Members()
+	assert.Nil(err)
+	assert.Empty(members)
+}
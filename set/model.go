@@ -1,5 +1,7 @@
 package set
 
+import "sort"
+
 type setModel struct {
 	m map[string]struct{}
 }
@@ -25,6 +27,24 @@ func (mod setModel) Contains(x []byte) (bool, error) {
 	return contains, nil
 }
 
+func (mod setModel) Len() (int64, error) {
+	return int64(len(mod.m)), nil
+}
+
+func (mod setModel) Members() ([][]byte, error) {
+	xs := make([]string, 0, len(mod.m))
+	for x := range mod.m {
+		xs = append(xs, x)
+	}
+	sort.Strings(xs)
+
+	members := make([][]byte, 0, len(xs))
+	for _, x := range xs {
+		members = append(members, []byte(x))
+	}
+	return members, nil
+}
+
 func (mod setModel) clone() setModel {
 	cp := makeSetModel()
 	for x := range mod.m {
@@ -1,24 +1,86 @@
 package set
 
 import (
+	"encoding/binary"
 	"fmt"
+	"sync"
 
+	"github.com/lyonssp/leveladt"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// keys of a set, relative to its namespace
+const (
+	pMember = "member" // followed by a member, holds nothing
+	pCount  = "count"  // holds the number of members
+)
+
+// Set is a set of byte strings backed by LevelDB. Members are stored as keys under the
+// namespace of the set, next to a counter that keeps the number of members. The
+// namespace is preceded by its length, so the keys of a set never start with the keys of
+// a set whose namespace extends its own.
 type Set struct {
 	ns  []byte
 	ldb *leveldb.DB
+
+	// serializes updates, so that the membership read by an update still holds when its
+	// batch is written. Other Sets of the same namespace are not serialized with this one.
+	l sync.Mutex
+}
+
+// NewSet returns the set stored under namespace ns. Updates are serialized by the
+// returned Set, so the count of members stays exact only while every update of the
+// namespace goes through the same Set; open each namespace once and share it. Sets
+// written by earlier releases must be upgraded with Migrate first.
+func NewSet(ns []byte, ldb *leveldb.DB) *Set {
+	return &Set{
+		ns:  ns,
+		ldb: ldb,
+	}
 }
 
 // Add includes the value x to the set
 func (s *Set) Add(x []byte) error {
-	return s.ldb.Put(s.key(x), []byte{}, nil)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	ok, err := s.Contains(x)
+	if err != nil || ok {
+		return err
+	}
+
+	n, err := s.Len()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(s.key(x), []byte{})
+	batch.Put(s.special(pCount), encodeCount(n+1))
+	return s.write(batch)
 }
 
 // Remove deletes the value x from the set
 func (s *Set) Remove(x []byte) error {
-	return s.ldb.Delete(s.key(x), nil)
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	ok, err := s.Contains(x)
+	if err != nil || !ok {
+		return err
+	}
+
+	n, err := s.Len()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(s.key(x))
+	batch.Put(s.special(pCount), encodeCount(n-1))
+	return s.write(batch)
 }
 
 // Contains returns true if x is in the set, and false otherwise
@@ -28,14 +90,133 @@ func (s *Set) Contains(x []byte) (bool, error) {
 		if err == leveldb.ErrNotFound {
 			return false, nil
 		}
-		return false, fmt.Errorf("leveldb get: %v", err)
+		return false, fmt.Errorf("leveldb get: %w", err)
 	}
 	return true, nil
 }
 
+// Len returns the number of members of the set
+func (s *Set) Len() (int64, error) {
+	v, err := s.ldb.Get(s.special(pCount), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodeCount(v)
+}
+
+// Members returns every member of the set, in byte order
+func (s *Set) Members() ([][]byte, error) {
+	it, err := s.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+
+	members := make([][]byte, 0)
+	for it.Next() {
+		members = append(members, it.Member())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Iterator walks the members of a set in byte order, as they were when the iterator was
+// created. An Iterator must be released once it is no longer used.
+type Iterator struct {
+	snap   *leveldb.Snapshot
+	iter   iterator.Iterator
+	prefix int // length of the key prefix that precedes each member
+	err    error
+}
+
+// Iterator returns an iterator over a snapshot of the set, so members added or removed
+// while iterating are not seen
+func (s *Set) Iterator() (*Iterator, error) {
+	snap, err := s.ldb.GetSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("leveldb snapshot: %w", err)
+	}
+
+	return &Iterator{
+		snap:   snap,
+		iter:   snap.NewIterator(util.BytesPrefix(s.special(pMember)), nil),
+		prefix: len(s.special(pMember)),
+	}, nil
+}
+
+// Next moves the iterator to the next member, and returns false once there are no more
+// members or an error occurred
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.iter.Next() {
+		return true
+	}
+	if err := it.iter.Error(); err != nil {
+		it.err = fmt.Errorf("leveldb iterate: %w", err)
+	}
+	return false
+}
+
+// Member returns the current member
+func (it *Iterator) Member() []byte {
+	return append([]byte{}, it.iter.Key()[it.prefix:]...)
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Release releases the snapshot held by the iterator
+func (it *Iterator) Release() {
+	it.iter.Release()
+	it.snap.Release()
+}
+
+func (s *Set) write(batch *leveldb.Batch) error {
+	if err := s.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
 func (s *Set) key(x []byte) []byte {
-	namespaced := make([]byte, len(x)+len(s.ns))
-	copy(namespaced[:len(s.ns)], s.ns)
-	copy(namespaced[len(s.ns):], x)
+	prefix := s.special(pMember)
+	namespaced := make([]byte, len(prefix)+len(x))
+	copy(namespaced, prefix)
+	copy(namespaced[len(prefix):], x)
 	return namespaced
 }
+
+// special encodes a special key, respecting the namespace of the set, which is preceded
+// by its uvarint length
+func (s *Set) special(name string) []byte {
+	var n [binary.MaxVarintLen64]byte
+	k := binary.PutUvarint(n[:], uint64(len(s.ns)))
+
+	namespaced := make([]byte, k+len(s.ns)+len(name))
+	copy(namespaced, n[:k])
+	copy(namespaced[k:], s.ns)
+	copy(namespaced[k+len(s.ns):], name)
+	return namespaced
+}
+
+func encodeCount(n int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(n))
+	return b
+}
+
+func decodeCount(b []byte) (int64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("malformed set count of size %d: %w", len(b), leveladt.ErrCorrupt)
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
@@ -3,6 +3,7 @@ package set
 import (
 	"fmt"
 	"io/ioutil"
+	"reflect"
 	"testing"
 
 	"github.com/leanovate/gopter"
@@ -33,7 +34,7 @@ func TestSetModel(t *testing.T) {
 			return true
 		},
 		GenCommandFunc: func(_ commands.State) gopter.Gen {
-			return gen.OneGenOf(genAddCommand, genRemoveCommand, genContainsCommand)
+			return gen.OneGenOf(genAddCommand, genRemoveCommand, genContainsCommand, genLenCommand, genMembersCommand)
 		},
 	}
 
@@ -69,6 +70,10 @@ var genContainsCommand gopter.Gen = func(params *gopter.GenParameters) *gopter.G
 	)
 }
 
+var genLenCommand = gen.Const(lenCommand{})
+
+var genMembersCommand = gen.Const(membersCommand{})
+
 type addCommand struct {
 	x []byte
 }
@@ -168,8 +173,72 @@ func (cmd containsCommand) String() string {
 	return fmt.Sprintf("contains(%s)", string(cmd.x))
 }
 
+type lenCommand struct{}
+
+func (cmd lenCommand) NextState(state commands.State) commands.State {
+	return state.(setModel).clone()
+}
+
+func (cmd lenCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd lenCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	n, err := sut.(*Set).Len()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return n
+}
+
+func (cmd lenCommand) PostCondition(state commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	model, _ := state.(setModel).Len()
+	system := result.(int64)
+	return gopter.NewPropResult(system == model, fmt.Sprintf("system != model: %d != %d", system, model))
+}
+
+func (cmd lenCommand) String() string {
+	return "len()"
+}
+
+type membersCommand struct{}
+
+func (cmd membersCommand) NextState(state commands.State) commands.State {
+	return state.(setModel).clone()
+}
+
+func (cmd membersCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd membersCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	members, err := sut.(*Set).Members()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return members
+}
+
+func (cmd membersCommand) PostCondition(state commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	model, _ := state.(setModel).Members()
+	system := result.([][]byte)
+	return gopter.NewPropResult(reflect.DeepEqual(system, model), fmt.Sprintf("system != model: %q != %q", system, model))
+}
+
+func (cmd membersCommand) String() string {
+	return "members()"
+}
+
 var (
 	_ commands.Command = addCommand{}
 	_ commands.Command = removeCommand{}
 	_ commands.Command = containsCommand{}
+	_ commands.Command = lenCommand{}
+	_ commands.Command = membersCommand{}
 )
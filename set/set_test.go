@@ -1,10 +1,12 @@
 package set
 
 import (
-	"github.com/stretchr/testify/assert"
-	"github.com/syndtr/goleveldb/leveldb"
+	"errors"
 	"io/ioutil"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func TestSet(t *testing.T) {
@@ -36,6 +38,109 @@ func TestSet(t *testing.T) {
 	assert.False(contains)
 }
 
+func TestLen(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s := NewSet([]byte("xxx"), db)
+
+	// adding a member twice or removing a missing one leaves the count alone
+	assert.Nil(s.Add([]byte("foo")))
+	assert.Nil(s.Add([]byte("foo")))
+	assert.Nil(s.Add([]byte("bar")))
+	assert.Nil(s.Remove([]byte("baz")))
+
+	n, err := s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(2), n)
+
+	assert.Nil(db.Close())
+	db, err = leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s = NewSet([]byte("xxx"), db)
+	assert.Nil(s.Remove([]byte("foo")))
+	assert.Nil(s.Remove([]byte("foo")))
+
+	n, err = s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(1), n)
+}
+
+func TestIterator(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s := NewSet([]byte("xxx"), db)
+	other := NewSet([]byte("xxy"), db)
+
+	for _, x := range []string{"foo", "bar", "baz"} {
+		assert.Nil(s.Add([]byte(x)))
+	}
+	assert.Nil(other.Add([]byte("qux")))
+
+	it, err := s.Iterator()
+	assert.Nil(err)
+	defer it.Release()
+
+	// updates after the iterator is created are not seen
+	assert.Nil(s.Remove([]byte("bar")))
+	assert.Nil(s.Add([]byte("quux")))
+
+	var got []string
+	for it.Next() {
+		got = append(got, string(it.Member()))
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"bar", "baz", "foo"}, got)
+
+	members, err := s.Members()
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("baz"), []byte("foo"), []byte("quux")}, members)
+}
+
+func TestClosed(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	s := Set{
+		ns:  []byte("xxx"),
+		ldb: db,
+	}
+
+	assert.Nil(db.Close())
+
+	err = s.Add([]byte("foo"))
+	assert.True(errors.Is(err, leveldb.ErrClosed))
+
+	err = s.Remove([]byte("foo"))
+	assert.True(errors.Is(err, leveldb.ErrClosed))
+
+	_, err = s.Contains([]byte("foo"))
+	assert.True(errors.Is(err, leveldb.ErrClosed))
+
+	_, err = s.Len()
+	assert.True(errors.Is(err, leveldb.ErrClosed))
+
+	_, err = s.Members()
+	assert.True(errors.Is(err, leveldb.ErrClosed))
+}
+
 func TestNamespacing(t *testing.T) {
 	t.Run("Add", func(t *testing.T) {
 		assert := assert.New(t)
@@ -96,3 +201,28 @@ func TestNamespacing(t *testing.T) {
 		assert.True(contains)
 	})
 }
+
+func TestNamespacingSharedPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "test")
+	assert.Nil(err)
+
+	db, err := leveldb.OpenFile(dir, nil)
+	assert.Nil(err)
+
+	// a namespace that extends another with the prefix of its members
+	user := NewSet([]byte("user"), db)
+	membership := NewSet([]byte("usermembership"), db)
+
+	assert.Nil(user.Add([]byte("alice")))
+	assert.Nil(membership.Add([]byte("gold")))
+
+	members, err := user.Members()
+	assert.Nil(err)
+	assert.Equal([][]byte{[]byte("alice")}, members)
+
+	n, err := user.Len()
+	assert.Nil(err)
+	assert.Equal(int64(len(members)), n)
+}
@@ -0,0 +1,54 @@
+package stack
+
+import (
+	"fmt"
+
+	"github.com/lyonssp/leveladt"
+)
+
+type stackModel struct {
+	ls         []string
+	lastPopped []byte
+}
+
+func makeStackModel() stackModel {
+	return stackModel{ls: make([]string, 0)}
+}
+
+func (mod *stackModel) Push(x []byte) error {
+	mod.ls = append(mod.ls, string(x))
+	return nil
+}
+
+func (mod *stackModel) Pop() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot pop from empty stack: %w", leveladt.ErrEmpty)
+	}
+
+	top := mod.ls[len(mod.ls)-1]
+	mod.lastPopped = []byte(top)
+	mod.ls = mod.ls[:len(mod.ls)-1]
+	return []byte(top), nil
+}
+
+func (mod stackModel) Peek() ([]byte, error) {
+	if len(mod.ls) <= 0 {
+		return nil, fmt.Errorf("cannot peek into empty stack: %w", leveladt.ErrEmpty)
+	}
+	return []byte(mod.ls[len(mod.ls)-1]), nil
+}
+
+func (mod *stackModel) Clear() error {
+	mod.ls = mod.ls[:0]
+	return nil
+}
+
+func (mod stackModel) size() int {
+	return len(mod.ls)
+}
+
+func (mod stackModel) clone() stackModel {
+	cp := make([]string, len(mod.ls))
+	copy(cp, mod.ls)
+	return stackModel{ls: cp, lastPopped: mod.lastPopped}
+}
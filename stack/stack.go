@@ -0,0 +1,191 @@
+package stack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// special key that holds the height of the stack, and the prefix of the keys that hold
+// its items
+const (
+	pHeight = "height" // number of items in the stack
+	pItem   = "item"   // followed by the big-endian position of an item, counted from the bottom
+)
+
+// Stack is a LIFO stack backed by LevelDB.
+//
+// Items are stored under their position counted from the bottom of the stack, and the
+// height is updated in the same batch as the item it covers, so push, pop and length are
+// all O(1).
+type Stack struct {
+	ns  []byte
+	ldb *leveldb.DB
+	l   sync.Mutex
+}
+
+func NewStack(ns []byte, ldb *leveldb.DB) *Stack {
+	return &Stack{
+		ns:  ns,
+		ldb: ldb,
+	}
+}
+
+// Push adds v to the top of the stack
+func (s *Stack) Push(v []byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	height, err := s.height()
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(s.item(height), v)
+	batch.Put(s.special(pHeight), encodeUint(height+1))
+	return s.write(batch)
+}
+
+// Pop removes and returns the item at the top of the stack
+func (s *Stack) Pop() ([]byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	height, err := s.height()
+	if err != nil {
+		return nil, err
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("cannot pop from empty stack: %w", leveladt.ErrEmpty)
+	}
+
+	v, err := s.get(height - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Delete(s.item(height - 1))
+	batch.Put(s.special(pHeight), encodeUint(height-1))
+	if err := s.write(batch); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Peek returns the item at the top of the stack without removing it
+func (s *Stack) Peek() ([]byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	height, err := s.height()
+	if err != nil {
+		return nil, err
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("cannot peek into empty stack: %w", leveladt.ErrEmpty)
+	}
+	return s.get(height - 1)
+}
+
+// Len returns the number of items in the stack
+func (s *Stack) Len() (int64, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	height, err := s.height()
+	return int64(height), err
+}
+
+// Clear removes every item from the stack in a single batch
+func (s *Stack) Clear() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	height, err := s.height()
+	if err != nil {
+		return err
+	}
+
+	// the range holds only positions below the height, so keys of stacks whose namespace
+	// extends this one are left alone
+	iter := s.ldb.NewIterator(&util.Range{Start: s.item(0), Limit: s.item(height)}, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("leveldb iterate: %w", err)
+	}
+	batch.Delete(s.special(pHeight))
+	return s.write(batch)
+}
+
+/*
+  convenience accessors that respect the stack namespace
+*/
+
+// height reads the durable height of the stack, which is zero if it was never written
+func (s *Stack) height() (uint64, error) {
+	v, err := s.ldb.Get(s.special(pHeight), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("leveldb get: %w", err)
+	}
+	return decodeUint(v)
+}
+
+// get reads the item at position i, which must exist
+func (s *Stack) get(i uint64) ([]byte, error) {
+	v, err := s.ldb.Get(s.item(i), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, fmt.Errorf("missing stack item %d: %w", i, leveladt.ErrCorrupt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("leveldb get: %w", err)
+	}
+	return v, nil
+}
+
+func (s *Stack) write(batch *leveldb.Batch) error {
+	if err := s.ldb.Write(batch, nil); err != nil {
+		return fmt.Errorf("leveldb write: %w", err)
+	}
+	return nil
+}
+
+// special encodes a special key or prefix, respecting the namespace of the stack
+func (s *Stack) special(name string) []byte {
+	namespaced := make([]byte, len(s.ns)+len(name))
+	copy(namespaced, s.ns)
+	copy(namespaced[len(s.ns):], name)
+	return namespaced
+}
+
+// item encodes the key of the item at position i
+func (s *Stack) item(i uint64) []byte {
+	return append(s.special(pItem), encodeUint(i)...)
+}
+
+// encodeUint returns the big-endian encoding of u, which sorts in numeric order
+func encodeUint(u uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, u)
+	return b
+}
+
+func decodeUint(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("malformed integer of length %d: %w", len(b), leveladt.ErrCorrupt)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
@@ -0,0 +1,335 @@
+package stack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/commands"
+	"github.com/leanovate/gopter/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const testNamespace = "test"
+
+func TestStackModel(t *testing.T) {
+	assert := assert.New(t)
+
+	test := &commands.ProtoCommands{
+		NewSystemUnderTestFunc: func(initialState commands.State) commands.SystemUnderTest {
+			dir, err := ioutil.TempDir("", "stack-*")
+			assert.Nil(err)
+
+			db, err := leveldb.OpenFile(dir, nil)
+			assert.Nil(err)
+
+			return &stackController{
+				dir:   dir,
+				ldb:   db,
+				stack: NewStack([]byte(testNamespace), db),
+			}
+		},
+		InitialStateGen: gen.Const(makeStackModel()),
+		InitialPreConditionFunc: func(_ commands.State) bool {
+			return true
+		},
+		GenCommandFunc: func(st commands.State) gopter.Gen {
+			return gen.Weighted([]gen.WeightedGen{
+				{Weight: 4, Gen: genPushCommand},
+				{Weight: 2, Gen: genPopCommand},
+				{Weight: 2, Gen: genPeekCommand},
+				{Weight: 2, Gen: genLenCommand},
+				{Weight: 1, Gen: genClearCommand},
+				{Weight: 2, Gen: genCrashCommand},
+			})
+		},
+	}
+
+	properties := gopter.NewProperties(gopter.DefaultTestParameters())
+	properties.Property("model", commands.Prop(test))
+	properties.TestingRun(t)
+}
+
+// genBytes generates arbitrary byte slices
+var genBytes gopter.Gen = gen.SliceOf(gen.UInt8())
+
+func genPushCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		pushCommand{
+			x: genBytes(params).Result.([]byte),
+		},
+		gopter.NoShrinker,
+	)
+}
+
+func genPopCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		popCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genPeekCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		peekCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genLenCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		lenCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genClearCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		clearCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+func genCrashCommand(params *gopter.GenParameters) *gopter.GenResult {
+	return gopter.NewGenResult(
+		crashCommand{},
+		gopter.NoShrinker,
+	)
+}
+
+type pushCommand struct {
+	x []byte
+}
+
+func (cmd pushCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	s := sut.(*stackController).stack
+	if err := s.Push(cmd.x); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd pushCommand) NextState(state commands.State) commands.State {
+	st := state.(stackModel).clone()
+	st.Push(cmd.x)
+	return st
+}
+
+func (cmd pushCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd pushCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd pushCommand) String() string {
+	return fmt.Sprintf("push(%q)", cmd.x)
+}
+
+type popCommand struct{}
+
+func (cmd popCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	s := sut.(*stackController).stack
+	top, err := s.Pop()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return top
+}
+
+func (cmd popCommand) NextState(state commands.State) commands.State {
+	st := state.(stackModel).clone()
+	st.Pop()
+	return st
+}
+
+func (cmd popCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want := st.(stackModel).lastPopped
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd popCommand) PreCondition(st commands.State) bool {
+	return st.(stackModel).size() > 0
+}
+
+func (cmd popCommand) String() string {
+	return "pop()"
+}
+
+type peekCommand struct{}
+
+func (cmd peekCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	s := sut.(*stackController).stack
+	top, err := s.Peek()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return top
+}
+
+func (cmd peekCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd peekCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.([]byte)
+	want, _ := st.(stackModel).Peek()
+	if !bytes.Equal(got, want) {
+		return gopter.NewPropResult(false, fmt.Sprintf("%q != %q", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd peekCommand) PreCondition(st commands.State) bool {
+	return st.(stackModel).size() > 0
+}
+
+func (cmd peekCommand) String() string {
+	return "peek()"
+}
+
+type lenCommand struct{}
+
+func (cmd lenCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	s := sut.(*stackController).stack
+	n, err := s.Len()
+	if err != nil {
+		return commands.Result(err)
+	}
+	return n
+}
+
+func (cmd lenCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd lenCommand) PostCondition(st commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+
+	got := result.(int64)
+	want := int64(st.(stackModel).size())
+	if got != want {
+		return gopter.NewPropResult(false, fmt.Sprintf("%d != %d", got, want))
+	}
+
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd lenCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd lenCommand) String() string {
+	return "len()"
+}
+
+type clearCommand struct{}
+
+func (cmd clearCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	s := sut.(*stackController).stack
+	if err := s.Clear(); err != nil {
+		return commands.Result(err)
+	}
+	return nil
+}
+
+func (cmd clearCommand) NextState(state commands.State) commands.State {
+	st := state.(stackModel).clone()
+	st.Clear()
+	return st
+}
+
+func (cmd clearCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd clearCommand) PreCondition(_ commands.State) bool {
+	return true
+}
+
+func (cmd clearCommand) String() string {
+	return "clear()"
+}
+
+type crashCommand struct{}
+
+func (cmd crashCommand) Run(sut commands.SystemUnderTest) commands.Result {
+	sc := sut.(*stackController)
+
+	// close LevelDB connection and release resources
+	sc.ldb.Close()
+
+	// create new LevelDB connection
+	db, err := leveldb.OpenFile(sc.dir, nil)
+	if err != nil {
+		return err
+	}
+
+	sc.ldb = db
+	sc.stack = NewStack([]byte(testNamespace), db)
+
+	return nil
+}
+
+func (cmd crashCommand) NextState(state commands.State) commands.State {
+	return state
+}
+
+func (cmd crashCommand) PostCondition(_ commands.State, result commands.Result) *gopter.PropResult {
+	if e, ok := result.(error); ok {
+		return &gopter.PropResult{Status: gopter.PropError, Error: e}
+	}
+	return gopter.NewPropResult(true, "")
+}
+
+func (cmd crashCommand) PreCondition(st commands.State) bool {
+	return true
+}
+
+func (cmd crashCommand) String() string {
+	return "crash()"
+}
+
+var (
+	_ commands.Command = pushCommand{}
+	_ commands.Command = popCommand{}
+	_ commands.Command = peekCommand{}
+	_ commands.Command = lenCommand{}
+	_ commands.Command = clearCommand{}
+	_ commands.Command = crashCommand{}
+)
+
+// stackController preserves the underlying reference to resources consumed by a
+// Stack to enable commands that represent restarts, filesystem failures, etc.
+type stackController struct {
+	dir   string      // root of LevelDB database
+	ldb   *leveldb.DB // current LevelDB connection
+	stack *Stack      // stack under test
+}
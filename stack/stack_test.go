@@ -0,0 +1,130 @@
+package stack
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/lyonssp/leveladt"
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func openTestDB(t *testing.T) *leveldb.DB {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestStack(t *testing.T) {
+	assert := assert.New(t)
+	s := NewStack([]byte("test"), openTestDB(t))
+
+	_, err := s.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+	_, err = s.Peek()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+
+	for _, v := range []string{"foo", "bar", "baz"} {
+		assert.Nil(s.Push([]byte(v)))
+	}
+
+	top, err := s.Peek()
+	assert.Nil(err)
+	assert.Equal([]byte("baz"), top)
+
+	for _, want := range []string{"baz", "bar"} {
+		got, err := s.Pop()
+		assert.Nil(err)
+		assert.Equal([]byte(want), got)
+	}
+
+	n, err := s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(1), n)
+
+	assert.Nil(s.Clear())
+
+	n, err = s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(0), n)
+
+	_, err = s.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+}
+
+func TestNamespacing(t *testing.T) {
+	assert := assert.New(t)
+	db := openTestDB(t)
+
+	a := NewStack([]byte("a"), db)
+	b := NewStack([]byte("b"), db)
+
+	assert.Nil(a.Push([]byte("foo")))
+	assert.Nil(b.Push([]byte("bar")))
+	assert.Nil(b.Clear())
+
+	got, err := a.Pop()
+	assert.Nil(err)
+	assert.Equal([]byte("foo"), got)
+}
+
+func TestNamespacingSharedPrefix(t *testing.T) {
+	assert := assert.New(t)
+	db := openTestDB(t)
+
+	// a namespace that extends another with the prefix of its items
+	a := NewStack([]byte("jobs"), db)
+	b := NewStack([]byte("jobsitems"), db)
+
+	assert.Nil(a.Push([]byte("foo")))
+	assert.Nil(b.Push([]byte("bar")))
+	assert.Nil(b.Push([]byte("baz")))
+	assert.Nil(a.Clear())
+
+	n, err := b.Len()
+	assert.Nil(err)
+	assert.Equal(int64(2), n)
+
+	for _, want := range []string{"baz", "bar"} {
+		got, err := b.Pop()
+		assert.Nil(err)
+		assert.Equal([]byte(want), got)
+	}
+}
+
+func TestConcurrentPush(t *testing.T) {
+	assert := assert.New(t)
+	s := NewStack([]byte("test"), openTestDB(t))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.Nil(s.Push([]byte("foo")))
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := s.Len()
+	assert.Nil(err)
+	assert.Equal(int64(400), n)
+
+	for i := 0; i < 400; i++ {
+		_, err := s.Pop()
+		assert.Nil(err)
+	}
+	_, err = s.Pop()
+	assert.True(errors.Is(err, leveladt.ErrEmpty))
+}